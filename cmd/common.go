@@ -1,21 +1,143 @@
 package cmd
 
 import (
+	"os"
+	"os/user"
+	"regexp"
+	"strings"
+
+	"github.com/cnrancher/cube-cli/util"
+
+	"github.com/pkg/errors"
+	"github.com/rancher/types/apis/management.cattle.io/v3"
 	"github.com/sirupsen/logrus"
 	"github.com/urfave/cli"
+	"gopkg.in/yaml.v2"
 )
 
 const (
-	APIServerKubeConfig    = "/var/lib/rancher/cube"
-	APIServerImage         = "cnrancher/cube-apiserver"
-	APIServerContainerName = "cube-apiserver"
-	APIServerPortDefault   = "9600"
-	RKEBaseConfigDefault   = "/var/lib/rancher/cube/rke_base.yml"
-	RKEConfigDefault       = "/var/lib/rancher/cube/rke_config.yml"
-	SSHKeyPathDefault      = "%s/.ssh/id_rsa"
-	KubeConfigLocation     = "/var/lib/rancher/cube/kube_config_rke_config.yml"
+	APIServerKubeConfig         = "/var/lib/rancher/cube"
+	APIServerImage              = "cnrancher/cube-apiserver"
+	APIServerTagLatest          = "latest"
+	APIServerContainerName      = "cube-apiserver"
+	APIServerPortDefault        = "9600"
+	APIServerBindAddressDefault = "0.0.0.0"
+	APIServerVersionPath        = "/version"
+	RKEBaseConfigName           = "rke_base.yml"
+	RKEConfigName               = "rke_config.yml"
+	SSHKeyPathDefault           = "%s/.ssh/id_rsa"
+	KubeConfigName              = "kube_config_rke_config.yml"
 )
 
+var releaseVersionRegexp = regexp.MustCompile(`^v[0-9]+\.[0-9]+\.[0-9]+(-rc[0-9]+)?$`)
+
+// APIServerTagDefault returns the api-server image tag matching the cli
+// version, the development builds have no matching image and use latest.
+func APIServerTagDefault(version string) string {
+	if releaseVersionRegexp.MatchString(version) {
+		return version
+	}
+	return APIServerTagLatest
+}
+
+// RKEBaseConfigDefault returns the rke base config location under the data directory.
+func RKEBaseConfigDefault() string {
+	return util.DataPath(RKEBaseConfigName)
+}
+
+// RKEConfigDefault returns the rke config location under the data directory.
+func RKEConfigDefault() string {
+	return util.DataPath(RKEConfigName)
+}
+
+// KubeConfigLocation returns the kubernetes config location under the data directory.
+func KubeConfigLocation() string {
+	return util.DataPath(KubeConfigName)
+}
+
+// readRKEConfig reads the rke config, falls back to the rke base config
+// when the cluster has not been configured yet.
+func readRKEConfig(rkeConfig string) (*v3.RancherKubernetesEngineConfig, error) {
+	if _, err := os.Stat(rkeConfig); err != nil {
+		return util.ReadRKEConfig(RKEBaseConfigDefault())
+	}
+	return util.ReadRKEConfig(rkeConfig)
+}
+
+// errSkipWrite is returned by an updateRKEConfig callback to leave the rke
+// config untouched without failing.
+var errSkipWrite = errors.New("skip rke config write")
+
+// updateRKEConfig runs the read-modify-write of the rke config while holding
+// its file lock, so that concurrent cube commands do not lose each other's changes.
+func updateRKEConfig(rkeConfig string, fn func(config *v3.RancherKubernetesEngineConfig) error) error {
+	return writeRKEConfig(rkeConfig, func() ([]byte, error) {
+		config, err := readRKEConfig(rkeConfig)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := fn(config); err != nil {
+			return nil, err
+		}
+		return yaml.Marshal(config)
+	})
+}
+
+// writeRKEConfig replaces the rke config with the content returned by fn
+// while holding its file lock, and records the new content as a revision.
+func writeRKEConfig(rkeConfig string, fn func() ([]byte, error)) error {
+	lock, err := util.LockFile(rkeConfig)
+	if err != nil {
+		return errors.Wrapf(err, "lock rke config %s", rkeConfig)
+	}
+	defer lock.Unlock()
+
+	username := currentUsername()
+	if err := util.InitHistory(rkeConfig, username); err != nil {
+		return errors.Wrapf(err, "init rke config history %s", rkeConfig)
+	}
+
+	content, err := fn()
+	if err != nil {
+		if err == errSkipWrite {
+			return nil
+		}
+		return err
+	}
+
+	if err := util.WriteRKEConfigContent(content, rkeConfig); err != nil {
+		return err
+	}
+
+	revision, err := util.RecordRevision(rkeConfig, commandLine(), username)
+	if err != nil {
+		return errors.Wrapf(err, "record rke config revision %s", rkeConfig)
+	}
+	logrus.Debugf("recorded rke config %s revision %d", rkeConfig, revision.Revision)
+
+	return nil
+}
+
+// commandLine returns the cube command being run, used to label config revisions.
+func commandLine() string {
+	return strings.Join(append([]string{"cube"}, os.Args[1:]...), " ")
+}
+
+// currentUsername returns the user running cube, preferring the user behind sudo.
+func currentUsername() string {
+	if sudoUser := os.Getenv("SUDO_USER"); sudoUser != "" {
+		return sudoUser
+	}
+
+	current, err := user.Current()
+	if err != nil {
+		return "unknown"
+	}
+
+	return current.Username
+}
+
 func defaultAction(fn func(ctx *cli.Context) error) func(ctx *cli.Context) error {
 	return func(ctx *cli.Context) error {
 		if ctx.Bool("help") {
@@ -23,10 +145,16 @@ func defaultAction(fn func(ctx *cli.Context) error) func(ctx *cli.Context) error
 			return nil
 		}
 
-		if err := fn(ctx); err != nil {
+		startAction(ctx)
+		err := fn(ctx)
+		if err != nil {
 			logrus.Errorf("cube error: %v", err)
-			return err
 		}
-		return nil
+		if structuredOutput() {
+			if writeErr := writeResult(err); writeErr != nil && err == nil {
+				return writeErr
+			}
+		}
+		return err
 	}
 }
@@ -9,9 +9,11 @@ import (
 
 var (
 	Commands = map[string]cli.Command{
-		"server": ServerCommand(),
-		"node":   NodeCommand(),
-		"rke":    RKECommand(),
+		"server":  ServerCommand(),
+		"node":    NodeCommand(),
+		"rke":     RKECommand(),
+		"context": ContextCommand(),
+		"config":  ConfigCommand(),
 	}
 	Flags = []cli.Flag{}
 )
@@ -60,6 +62,8 @@ func argumentsCompleter(args []string) []prompt.Suggest {
 			subcommands := []prompt.Suggest{
 				{Text: "run", Description: "Run RancherCUBE api-server"},
 				{Text: "status", Description: "Status the RancherCUBE api-server"},
+				{Text: "logs", Description: "Show the RancherCUBE api-server logs"},
+				{Text: "upgrade", Description: "Upgrade the RancherCUBE api-server image"},
 				{Text: "stop", Description: "Stop the RancherCUBE api-server"},
 				{Text: "rm", Description: "Remove the RancherCUBE api-server"},
 			}
@@ -70,6 +74,9 @@ func argumentsCompleter(args []string) []prompt.Suggest {
 			subcommands := []prompt.Suggest{
 				{Text: "ls", Description: "List the Rancher Kubernetes Engine Nodes"},
 				{Text: "add", Description: "Add the Rancher Kubernetes Engine Node"},
+				{Text: "update", Description: "Update the Rancher Kubernetes Engine Node"},
+				{Text: "import", Description: "Import the Rancher Kubernetes Engine Nodes from a file"},
+				{Text: "export", Description: "Export the Rancher Kubernetes Engine Nodes to a file"},
 				{Text: "rm", Description: "Remove the Rancher Kubernetes Engine Node"},
 			}
 			return prompt.FilterHasPrefix(subcommands, args[1], true)
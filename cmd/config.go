@@ -0,0 +1,191 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+
+	"github.com/cnrancher/cube-cli/cmd/pkg/table"
+	"github.com/cnrancher/cube-cli/util"
+
+	"github.com/rancher/types/apis/management.cattle.io/v3"
+	"github.com/urfave/cli"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	ConfigDescription = `
+Management Rancher Kubernetes Engine config revisions.
+
+Every change made by the cube commands to the rke config is recorded as a revision.
+
+Example:
+	# List the rke config revisions
+	$ cube config history
+	# Show the changes between a revision and the current rke config
+	$ cube config diff <revision>
+	# Show the changes between two revisions
+	$ cube config diff <revision> <revision>
+	# Restore the rke config of a revision
+	$ cube config rollback <revision>
+`
+)
+
+func ConfigCommand() cli.Command {
+	return cli.Command{
+		Name:        "config",
+		Usage:       "Management Rancher Kubernetes Engine config revisions",
+		Description: ConfigDescription,
+		Flags:       table.WriterConfigFlags(),
+		Action:      defaultAction(configHistory),
+		Subcommands: []cli.Command{
+			{
+				Name:        "history",
+				Usage:       "List the Rancher Kubernetes Engine config revisions",
+				Description: "List the Rancher Kubernetes Engine config revisions",
+				Flags:       table.WriterConfigFlags(),
+				Action:      defaultAction(configHistory),
+			},
+			{
+				Name:        "diff",
+				Usage:       "Show the Rancher Kubernetes Engine config changes since a revision",
+				Description: "Show the changes between a revision and the current rke config, or between two revisions",
+				ArgsUsage:   "<revision> [revision]",
+				Action:      defaultAction(configDiff),
+			},
+			{
+				Name:        "rollback",
+				Usage:       "Restore the Rancher Kubernetes Engine config of a revision",
+				Description: "Restore the Rancher Kubernetes Engine config of a revision, the rollback is recorded as a new revision",
+				ArgsUsage:   "<revision>",
+				Action:      defaultAction(configRollback),
+			},
+		},
+	}
+}
+
+func configHistory(ctx *cli.Context) error {
+	cubeCtx, err := currentContext(ctx)
+	if err != nil {
+		return err
+	}
+	addTarget(cubeCtx.RKEConfig)
+
+	history, err := util.ReadHistory(cubeCtx.RKEConfig)
+	if err != nil {
+		return err
+	}
+
+	writer := table.NewConfigWriter([][]string{
+		{"REVISION", "{{.Revision}}", table.PresetNarrow},
+		{"TIMESTAMP", "{{.Timestamp | date}}"},
+		{"USER", "{{.User}}"},
+		{"COMMAND", "{{.Command}}", table.PresetNarrow},
+	}, ctx)
+	collectOutput(writer)
+
+	for _, revision := range history.Revisions {
+		writer.Write(revision)
+	}
+
+	return writer.Close()
+}
+
+func parseRevision(arg string) (int, error) {
+	revision, err := strconv.Atoi(arg)
+	if err != nil || revision <= 0 {
+		return 0, usageErrorf("invalid revision %s", arg)
+	}
+	return revision, nil
+}
+
+func configDiff(ctx *cli.Context) error {
+	args := ctx.Args()
+	if len(args) < 1 {
+		return usageErrorf("cube config diff: no arguments")
+	}
+
+	cubeCtx, err := currentContext(ctx)
+	if err != nil {
+		return err
+	}
+	addTarget(cubeCtx.RKEConfig)
+
+	fromRevision, err := parseRevision(args[0])
+	if err != nil {
+		return prefixError("cube config diff", err)
+	}
+	from, err := util.ReadRevision(cubeCtx.RKEConfig, fromRevision)
+	if err != nil {
+		return prefixError("cube config diff", err)
+	}
+
+	toName := cubeCtx.RKEConfig
+	var to []byte
+	if len(args) > 1 {
+		toRevision, err := parseRevision(args[1])
+		if err != nil {
+			return prefixError("cube config diff", err)
+		}
+		to, err = util.ReadRevision(cubeCtx.RKEConfig, toRevision)
+		if err != nil {
+			return prefixError("cube config diff", err)
+		}
+		toName = "revision " + args[1]
+	} else {
+		to, err = ioutil.ReadFile(cubeCtx.RKEConfig)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	diff := util.LineDiff("revision "+args[0], toName, string(from), string(to))
+	if structuredOutput() {
+		setData(diff)
+		return nil
+	}
+
+	fmt.Print(diff)
+	return nil
+}
+
+func configRollback(ctx *cli.Context) error {
+	args := ctx.Args()
+	if len(args) < 1 {
+		return usageErrorf("cube config rollback: no arguments")
+	}
+
+	cubeCtx, err := currentContext(ctx)
+	if err != nil {
+		return err
+	}
+	addTarget(cubeCtx.RKEConfig)
+
+	revision, err := parseRevision(args[0])
+	if err != nil {
+		return prefixError("cube config rollback", err)
+	}
+
+	content, err := util.ReadRevision(cubeCtx.RKEConfig, revision)
+	if err != nil {
+		return prefixError("cube config rollback", err)
+	}
+
+	// the revision is checked but written as it is stored, so that the fields
+	// unknown to cube and the key order survive the rollback
+	restored := v3.RancherKubernetesEngineConfig{}
+	if err := yaml.Unmarshal(content, &restored); err != nil {
+		return fmt.Errorf("cube config rollback: revision %d is not a valid rke config: %v", revision, err)
+	}
+
+	err = writeRKEConfig(cubeCtx.RKEConfig, func() ([]byte, error) {
+		return content, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	addChange("rke config %s restored to revision %d", cubeCtx.RKEConfig, revision)
+	return nil
+}
@@ -0,0 +1,372 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/cnrancher/cube-cli/cmd/pkg/table"
+	"github.com/cnrancher/cube-cli/docker"
+	"github.com/cnrancher/cube-cli/util"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+const (
+	ContextDescription = `
+Management RancherCUBE cluster contexts.
+
+Example:
+	# List the contexts
+	$ cube context ls
+	# Add a context, the rke config defaults to <data-dir>/contexts/<name>/rke_config.yml
+	$ cube context add <name> --rke-config <rke_config.yml> --docker-host unix:///var/run/docker.sock
+	# Select the context used by node, server and rke commands
+	$ cube context use <name>
+	# Print the selected context
+	$ cube context current
+	# Remove a context
+	$ cube context rm <name>
+`
+
+	ContextFlag       = "context"
+	ContextDirName    = "contexts"
+	RKEConfigFlag     = "rke-config"
+	KubeConfigFlag    = "kube-config"
+	DockerHostFlag    = "docker-host"
+	ContainerNameFlag = "container-name"
+)
+
+type ContextOutput struct {
+	Context util.Context `yaml:"context,omitempty" json:"context,omitempty"`
+	Current bool         `yaml:"current,omitempty" json:"current,omitempty"`
+}
+
+func ContextCommand() cli.Command {
+	return cli.Command{
+		Name:        "context",
+		Aliases:     []string{"ctx"},
+		Usage:       "Management RancherCUBE cluster contexts",
+		Description: ContextDescription,
+		Flags:       table.WriterContextFlags(),
+		Action:      defaultAction(contextLs),
+		Subcommands: []cli.Command{
+			{
+				Name:        "ls",
+				Usage:       "List the RancherCUBE cluster contexts",
+				Description: "List the RancherCUBE cluster contexts",
+				Flags:       table.WriterContextFlags(),
+				Action:      defaultAction(contextLs),
+			},
+			{
+				Name:        "use",
+				Usage:       "Select the RancherCUBE cluster context",
+				Description: "Select the RancherCUBE cluster context",
+				ArgsUsage:   "<name>",
+				Action:      defaultAction(contextUse),
+			},
+			{
+				Name:        "add",
+				Usage:       "Add the RancherCUBE cluster context",
+				Description: "Add the RancherCUBE cluster context",
+				ArgsUsage:   "<name>",
+				Flags: []cli.Flag{
+					cli.StringFlag{
+						Name:  RKEConfigFlag,
+						Usage: "Specify context rke config location, default to <data-dir>/" + ContextDirName + "/<name>/" + RKEConfigName,
+					},
+					cli.StringFlag{
+						Name:  KubeConfigFlag,
+						Usage: "Specify context kubernetes config location, default to kube_config_<rke-config> beside the rke config",
+					},
+					cli.StringFlag{
+						Name:  DockerHostFlag,
+						Usage: "Specify context docker host running the api-server, default to DOCKER_HOST or the system-docker socket falling back to the docker socket",
+					},
+					cli.StringFlag{
+						Name:  ContainerNameFlag,
+						Usage: "Specify context api-server container name, default to " + APIServerContainerName + "-<name>",
+					},
+				},
+				Action: defaultAction(contextAdd),
+			},
+			{
+				Name:        "rm",
+				Usage:       "Remove the RancherCUBE cluster context",
+				Description: "Remove the RancherCUBE cluster context",
+				ArgsUsage:   "<name>",
+				Action:      defaultAction(contextRm),
+			},
+			{
+				Name:        "current",
+				Usage:       "Print the selected RancherCUBE cluster context",
+				Description: "Print the selected RancherCUBE cluster context",
+				Action:      defaultAction(contextCurrent),
+			},
+		},
+	}
+}
+
+// defaultContext returns the context used when no other context is selected,
+// it points at the files under the data directory.
+func defaultContext() util.Context {
+	return util.Context{
+		Name:          util.DefaultContextName,
+		RKEConfig:     RKEConfigDefault(),
+		KubeConfig:    KubeConfigLocation(),
+		DockerHost:    docker.DefaultHost(),
+		ContainerName: APIServerContainerName,
+	}
+}
+
+// contextKubeConfig returns the kubernetes config rke writes for the rke config.
+func contextKubeConfig(rkeConfig string) string {
+	return filepath.Join(filepath.Dir(rkeConfig), "kube_config_"+filepath.Base(rkeConfig))
+}
+
+// updateContextConfig runs the read-modify-write of the context file while
+// holding its file lock, so that concurrent cube commands do not lose each other's changes.
+func updateContextConfig(fn func(config *util.ContextConfig) error) error {
+	contextFile := util.ContextFile()
+	lock, err := util.LockFile(contextFile)
+	if err != nil {
+		return errors.Wrapf(err, "lock context file %s", contextFile)
+	}
+	defer lock.Unlock()
+
+	config, err := util.ReadContextConfig(contextFile)
+	if err != nil {
+		return err
+	}
+
+	if err := fn(config); err != nil {
+		return err
+	}
+	return util.WriteContextConfig(config, contextFile)
+}
+
+// currentContextName returns the name of the context selected by the global
+// flag, or by the context file when the flag is not set.
+func currentContextName(ctx *cli.Context, config *util.ContextConfig) string {
+	name := ctx.GlobalString(ContextFlag)
+	if name == "" {
+		name = config.Current
+	}
+	if name == "" {
+		name = util.DefaultContextName
+	}
+	return name
+}
+
+// currentContext resolves the context selected by the global flag, or by the
+// context file when the flag is not set.
+func currentContext(ctx *cli.Context) (*util.Context, error) {
+	config, err := util.ReadContextConfig(util.ContextFile())
+	if err != nil {
+		return nil, err
+	}
+
+	name := currentContextName(ctx, config)
+
+	var cubeCtx util.Context
+	if name == util.DefaultContextName {
+		cubeCtx = defaultContext()
+	} else {
+		found, ok := config.Get(name)
+		if !ok {
+			return nil, notFoundErrorf("context %s not found", name)
+		}
+
+		cubeCtx = *found
+		if cubeCtx.RKEConfig == "" {
+			cubeCtx.RKEConfig = util.DataPath(ContextDirName, name, RKEConfigName)
+		}
+		if cubeCtx.KubeConfig == "" {
+			cubeCtx.KubeConfig = contextKubeConfig(cubeCtx.RKEConfig)
+		}
+		if cubeCtx.DockerHost == "" {
+			cubeCtx.DockerHost = docker.DefaultHost()
+		}
+		if cubeCtx.ContainerName == "" {
+			cubeCtx.ContainerName = APIServerContainerName + "-" + name
+		}
+	}
+
+	// the global flag overrides the docker host of every context
+	if host := ctx.GlobalString(DockerHostFlag); host != "" {
+		cubeCtx.DockerHost = host
+	}
+	cubeCtx.DockerHost = docker.ResolveHost(cubeCtx.DockerHost)
+
+	return &cubeCtx, nil
+}
+
+func contextLs(ctx *cli.Context) error {
+	config, err := util.ReadContextConfig(util.ContextFile())
+	if err != nil {
+		return err
+	}
+
+	// a missing selected context is reported but does not hide the others
+	current := currentContextName(ctx, config)
+	if _, ok := config.Get(current); !ok && current != util.DefaultContextName {
+		logrus.Warnf("cube context ls: context %s not found", current)
+	}
+
+	writer := table.NewContextWriter([][]string{
+		{"NAME", "{{.Context.Name}}", table.PresetNarrow},
+		{"CURRENT", "{{.Current | current}}", table.PresetNarrow},
+		{"RKE CONFIG", "{{.Context.RKEConfig}}"},
+		{"KUBE CONFIG", "{{.Context.KubeConfig}}"},
+		{"DOCKER HOST", "{{.Context.DockerHost}}"},
+		{"CONTAINER NAME", "{{.Context.ContainerName}}"},
+	}, ctx)
+	collectOutput(writer)
+
+	writer.Write(ContextOutput{
+		Context: defaultContext(),
+		Current: current == util.DefaultContextName,
+	})
+
+	for _, cubeCtx := range config.Contexts {
+		writer.Write(ContextOutput{
+			Context: cubeCtx,
+			Current: current == cubeCtx.Name,
+		})
+	}
+
+	return writer.Close()
+}
+
+func contextUse(ctx *cli.Context) error {
+	args := ctx.Args()
+	if len(args) < 1 {
+		return usageErrorf("cube context use: no arguments")
+	}
+	name := args[0]
+	addTarget(name)
+
+	err := updateContextConfig(func(config *util.ContextConfig) error {
+		if name == util.DefaultContextName {
+			config.Current = ""
+			return nil
+		}
+		if _, ok := config.Get(name); !ok {
+			return notFoundErrorf("cube context use: context %s not found", name)
+		}
+		config.Current = name
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	addChange("context %s selected", name)
+	return nil
+}
+
+func contextAdd(ctx *cli.Context) error {
+	args := ctx.Args()
+	if len(args) < 1 {
+		return usageErrorf("cube context add: no arguments")
+	}
+
+	name := args[0]
+	if "" == name {
+		return usageErrorf("cube context add: require name")
+	}
+	addTarget(name)
+	if name == util.DefaultContextName {
+		return conflictErrorf("cube context add: %s is reserved for the data directory context", name)
+	}
+	// the name is a directory under the data directory and part of the container name
+	if errs := validation.IsDNS1123Label(name); len(errs) > 0 {
+		return usageErrorf("cube context add: context name %s is invalid: %s", name, strings.Join(errs, ", "))
+	}
+
+	rkeConfig := ctx.String(RKEConfigFlag)
+	if rkeConfig == "" {
+		rkeConfig = util.DataPath(ContextDirName, name, RKEConfigName)
+	}
+	rkeConfig, err := filepath.Abs(rkeConfig)
+	if err != nil {
+		return err
+	}
+
+	kubeConfig := ctx.String(KubeConfigFlag)
+	if kubeConfig == "" {
+		kubeConfig = contextKubeConfig(rkeConfig)
+	}
+	kubeConfig, err = filepath.Abs(kubeConfig)
+	if err != nil {
+		return err
+	}
+
+	containerName := ctx.String(ContainerNameFlag)
+	if containerName == "" {
+		containerName = APIServerContainerName + "-" + name
+	}
+
+	err = updateContextConfig(func(config *util.ContextConfig) error {
+		if _, ok := config.Get(name); ok {
+			return conflictErrorf("cube context add: context %s already exist", name)
+		}
+
+		config.Contexts = append(config.Contexts, util.Context{
+			Name:          name,
+			RKEConfig:     rkeConfig,
+			KubeConfig:    kubeConfig,
+			DockerHost:    ctx.String(DockerHostFlag),
+			ContainerName: containerName,
+		})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	addChange("context %s added", name)
+	return nil
+}
+
+func contextRm(ctx *cli.Context) error {
+	args := ctx.Args()
+	if len(args) < 1 {
+		return usageErrorf("cube context remove: no arguments")
+	}
+	name := args[0]
+	addTarget(name)
+
+	if name == util.DefaultContextName {
+		return conflictErrorf("cube context remove: can not remove the %s context", name)
+	}
+
+	err := updateContextConfig(func(config *util.ContextConfig) error {
+		if !config.Remove(name) {
+			return notFoundErrorf("cube context remove: context %s not found", name)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	addChange("context %s removed", name)
+	return nil
+}
+
+func contextCurrent(ctx *cli.Context) error {
+	cubeCtx, err := currentContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	addTarget(cubeCtx.Name)
+	if structuredOutput() {
+		setData(cubeCtx)
+		return nil
+	}
+
+	fmt.Println(cubeCtx.Name)
+	return nil
+}
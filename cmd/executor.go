@@ -5,6 +5,8 @@ import (
 	"os"
 	"os/exec"
 	"strings"
+
+	"github.com/cnrancher/cube-cli/util"
 )
 
 func Executor(s string) {
@@ -18,6 +20,8 @@ func Executor(s string) {
 	}
 
 	cmd := exec.Command("/bin/sh", "-c", "cube "+s)
+	// keep the data directory the prompt was started with
+	cmd.Env = append(os.Environ(), util.DataDirEnv+"="+util.DataDir())
 	cmd.Stdin = os.Stdin
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
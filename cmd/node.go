@@ -2,18 +2,24 @@ package cmd
 
 import (
 	"fmt"
+	"io/ioutil"
 	"os"
 	"os/user"
 	"strings"
+	"time"
 
 	"github.com/cnrancher/cube-cli/cmd/pkg/table"
 	"github.com/cnrancher/cube-cli/util"
 
 	"github.com/cnrancher/cube-cli/k8s"
+	"github.com/pkg/errors"
+	"github.com/rancher/rke/services"
 	"github.com/rancher/types/apis/management.cattle.io/v3"
 	"github.com/sirupsen/logrus"
 	"github.com/urfave/cli"
+	"gopkg.in/yaml.v2"
 	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
 )
 
 const (
@@ -23,20 +29,89 @@ Management Rancher Kubernetes Engine Node.
 Example:
 	# List the Rancher Kubernetes Engine Nodes
 	$ cube node ls
+	# List the etcd Rancher Kubernetes Engine Nodes with a few columns, sorted by address
+	$ cube node ls --filter role=etcd --columns address,role,ready --sort-by address
+	# List the Rancher Kubernetes Engine Nodes with every column
+	$ cube node ls --format wide
 	# Add the Rancher Kubernetes Engine Node
 	$ cube node add <address> --roles worker,etcd --user rancher --ssh-key-path <user_directory>/.ssh/id_rsa
-	# Remove the Rancher Kubernetes Engine Node
-	$ cube node rm <address>
+	# Add the Rancher Kubernetes Engine Node with a custom ssh port, a private address and labels
+	$ cube node add <address> --port 2222 --internal-address 10.0.0.2 --label zone=a --label disk=ssd
+	# Update the Rancher Kubernetes Engine Node, only the given fields are changed
+	$ cube node update <address> --add-role etcd --remove-role worker --label zone=b --unlabel disk
+	# Preview and import the Rancher Kubernetes Engine Nodes from a csv, yaml or ansible ini file
+	$ cube node import nodes.csv --dry-run
+	$ cube node import inventory.ini
+	# Export the Rancher Kubernetes Engine Nodes
+	$ cube node export --format csv nodes.csv
+	# Remove the Rancher Kubernetes Engine Nodes
+	$ cube node rm <address> [address...]
+	# Remove the Rancher Kubernetes Engine Nodes matching a label selector
+	$ cube node rm --selector zone=a,disk!=ssd
+	# Remove the last etcd or controlplane Rancher Kubernetes Engine Node
+	$ cube node rm <address> --force
+	# Cordon and drain the Kubernetes node before removing it
+	$ cube node rm <address> --drain --grace-period 30 --drain-timeout 10m
+	# Drain the Kubernetes node even if pods not managed by a controller or emptyDir data are lost
+	$ cube node rm <address> --drain --delete-unmanaged --delete-emptydir-data
 `
-	Address    = "address"
-	Roles      = "roles"
-	User       = "user"
-	SSHKeyPath = "ssh-key-path"
+	Address          = "address"
+	Roles            = "roles"
+	User             = "user"
+	SSHKeyPath       = "ssh-key-path"
+	Port             = "port"
+	InternalAddress  = "internal-address"
+	HostnameOverride = "hostname-override"
+	DockerSocket     = "docker-socket"
+	SSHAgentAuth     = "ssh-agent-auth"
+	SSHKey           = "ssh-key"
+	Label            = "label"
+	AddRole          = "add-role"
+	RemoveRole       = "remove-role"
+	Unlabel          = "unlabel"
+	InventoryFormat  = "format"
+	DryRun           = "dry-run"
+	Drain            = "drain"
+	Force            = "force"
+	DeleteUnmanaged  = "delete-unmanaged"
+	DeleteEmptyDir   = "delete-emptydir-data"
+	DrainTimeout     = "drain-timeout"
+	GracePeriod      = "grace-period"
+	Selector         = "selector"
+)
+
+const (
+	NodeStateSynced    = "SYNCED"
+	NodeStatePending   = "PENDING"
+	NodeStateUnmanaged = "UNMANAGED"
+	NodeStateUnknown   = "UNKNOWN"
+
+	Timeout        = "timeout"
+	TimeoutDefault = 10 * time.Second
+
+	nodeRoleLabelPrefix = "node-role.kubernetes.io/"
 )
 
 type NodeOutput struct {
 	Config v3.RKEConfigNode `yaml:"config,omitempty" json:"config,omitempty"`
 	Sync   bool             `yaml:"sync,omitempty" json:"sync,omitempty"`
+	// State is SYNCED when the node is in both the rke config and kubernetes,
+	// PENDING when only in the rke config and UNMANAGED when only in kubernetes
+	State  string     `yaml:"state,omitempty" json:"state,omitempty"`
+	Status NodeStatus `yaml:"status,omitempty" json:"status,omitempty"`
+}
+
+// NodeStatus is the kubernetes view of a node.
+type NodeStatus struct {
+	Name             string     `yaml:"name,omitempty" json:"name,omitempty"`
+	Ready            string     `yaml:"ready,omitempty" json:"ready,omitempty"`
+	KubeletVersion   string     `yaml:"kubeletVersion,omitempty" json:"kubeletVersion,omitempty"`
+	OSImage          string     `yaml:"osImage,omitempty" json:"osImage,omitempty"`
+	ContainerRuntime string     `yaml:"containerRuntime,omitempty" json:"containerRuntime,omitempty"`
+	CPU              string     `yaml:"cpu,omitempty" json:"cpu,omitempty"`
+	Memory           string     `yaml:"memory,omitempty" json:"memory,omitempty"`
+	Created          *time.Time `yaml:"created,omitempty" json:"created,omitempty"`
+	Taints           []string   `yaml:"taints,omitempty" json:"taints,omitempty"`
 }
 
 func assembleSSHKeyPath() string {
@@ -55,14 +130,14 @@ func NodeCommand() cli.Command {
 		Aliases:     []string{"n"},
 		Usage:       "Management Rancher Kubernetes Engine Node",
 		Description: NodeDescription,
-		Flags:       table.WriterNodeFlags(),
+		Flags:       nodeLsFlags(),
 		Action:      defaultAction(nodeLs),
 		Subcommands: []cli.Command{
 			{
 				Name:        "ls",
 				Usage:       "List the Rancher Kubernetes Engine Nodes",
 				Description: "List the Rancher Kubernetes Engine Nodes",
-				Flags:       table.WriterNodeFlags(),
+				Flags:       nodeLsFlags(),
 				Action:      defaultAction(nodeLs),
 			},
 			{
@@ -70,206 +145,916 @@ func NodeCommand() cli.Command {
 				Usage:       "Add the Rancher Kubernetes Engine Node",
 				Description: "Add the Rancher Kubernetes Engine Node",
 				ArgsUsage:   "<address>",
+				Flags:       nodeFlags(true),
+				Action:      defaultAction(nodeAdd),
+			},
+			{
+				Name:        "update",
+				Usage:       "Update the Rancher Kubernetes Engine Node",
+				Description: "Update the Rancher Kubernetes Engine Node, only the fields of the given flags are changed",
+				ArgsUsage:   "<address>",
+				Flags: append(nodeFlags(false),
+					cli.StringFlag{
+						Name:  AddRole,
+						Usage: "Specify node roles to add",
+					},
+					cli.StringFlag{
+						Name:  RemoveRole,
+						Usage: "Specify node roles to remove",
+					},
+					cli.StringSliceFlag{
+						Name:  Unlabel,
+						Usage: "Specify node label key to remove, can be repeated",
+					},
+				),
+				Action: defaultAction(nodeUpdate),
+			},
+			{
+				Name:        "import",
+				Usage:       "Import the Rancher Kubernetes Engine Nodes from a file",
+				Description: "Import the Rancher Kubernetes Engine Nodes from a csv, yaml or ansible ini file, existing nodes are updated with the given fields",
+				ArgsUsage:   "<file>",
 				Flags: []cli.Flag{
 					cli.StringFlag{
-						Name:  Roles,
-						Value: "controlplane,worker,etcd",
-						Usage: "Specify node roles",
+						Name:  InventoryFormat,
+						Usage: "Specify file format, 'csv', 'yaml' or 'ini', default to guess from the file extension",
+					},
+					cli.BoolFlag{
+						Name:  DryRun,
+						Usage: "Only print the rke config changes",
 					},
 					cli.StringFlag{
 						Name:  User,
 						Value: "rancher",
-						Usage: "Specify node user",
+						Usage: "Specify node user for the nodes without one",
 					},
 					cli.StringFlag{
 						Name:  SSHKeyPath,
 						Value: assembleSSHKeyPath(),
-						Usage: "Specify node ssh key path",
+						Usage: "Specify node ssh key path for the nodes without one",
 					},
 				},
-
-				Action: defaultAction(nodeAdd),
+				Action: defaultAction(nodeImport),
+			},
+			{
+				Name:        "export",
+				Usage:       "Export the Rancher Kubernetes Engine Nodes to a file",
+				Description: "Export the Rancher Kubernetes Engine Nodes as csv, yaml or ansible ini, to stdout when no file is given",
+				ArgsUsage:   "[file]",
+				Flags: []cli.Flag{
+					cli.StringFlag{
+						Name:  InventoryFormat,
+						Value: util.InventoryYAML,
+						Usage: "Specify file format, 'csv', 'yaml' or 'ini'",
+					},
+				},
+				Action: defaultAction(nodeExport),
 			},
 			{
 				Name:        "rm",
 				Usage:       "Remove the Rancher Kubernetes Engine Node",
-				Description: "Remove the Rancher Kubernetes Engine Node",
-				ArgsUsage:   "<address>",
-				Action:      defaultAction(nodeRm),
+				Description: "Remove the Rancher Kubernetes Engine Nodes by address or label selector and delete their Kubernetes nodes, optionally cordon and drain the Kubernetes nodes first",
+				ArgsUsage:   "<address> [address...]",
+				Flags: []cli.Flag{
+					cli.StringFlag{
+						Name:  Selector + ", l",
+						Usage: "Remove the nodes matching the label selector, e.g. 'zone=a,disk!=ssd'",
+					},
+					cli.BoolFlag{
+						Name:  Drain,
+						Usage: "Cordon and drain the Kubernetes nodes before removing them",
+					},
+					cli.BoolFlag{
+						Name:  Force,
+						Usage: "Remove the last etcd or controlplane node",
+					},
+					cli.BoolFlag{
+						Name:  DeleteUnmanaged,
+						Usage: "Evict the pods not managed by a controller when draining, they are lost for good",
+					},
+					cli.IntFlag{
+						Name:  GracePeriod,
+						Value: -1,
+						Usage: "Specify the pod termination grace period in seconds when draining, negative uses the pod setting",
+					},
+					cli.BoolFlag{
+						Name:  DeleteEmptyDir,
+						Usage: "Evict the pods using emptyDir volumes when draining, their local data is lost",
+					},
+					cli.DurationFlag{
+						Name:  DrainTimeout,
+						Value: k8s.DrainTimeoutDefault,
+						Usage: "Specify the timeout of the whole drain of a node",
+					},
+					cli.DurationFlag{
+						Name:  Timeout,
+						Value: TimeoutDefault,
+						Usage: "Specify the timeout of the kubernetes api call",
+					},
+				},
+				Action: defaultAction(nodeRm),
 			},
 		},
 	}
 }
 
-func nodeLs(ctx *cli.Context) error {
-	config := &v3.RancherKubernetesEngineConfig{}
-	var err error
+func nodeLsFlags() []cli.Flag {
+	return append([]cli.Flag{
+		cli.DurationFlag{
+			Name:  Timeout,
+			Value: TimeoutDefault,
+			Usage: "Specify the timeout of the kubernetes api call",
+		},
+	}, table.WriterNodeFlags()...)
+}
 
-	if _, fErr := os.Stat(RKEConfigDefault); fErr != nil {
-		config, err = util.ReadRKEConfig(RKEBaseConfigDefault)
-	} else {
-		config, err = util.ReadRKEConfig(RKEConfigDefault)
+// nodeFlags returns the flags mapping to the RKEConfigNode fields, the
+// defaults are left out for commands changing existing nodes.
+func nodeFlags(withDefaults bool) []cli.Flag {
+	roles, user, sshKeyPath := "", "", ""
+	if withDefaults {
+		roles, user, sshKeyPath = "controlplane,worker,etcd", "rancher", assembleSSHKeyPath()
 	}
 
+	return []cli.Flag{
+		cli.StringFlag{
+			Name:  Roles,
+			Value: roles,
+			Usage: "Specify node roles",
+		},
+		cli.StringFlag{
+			Name:  User,
+			Value: user,
+			Usage: "Specify node user",
+		},
+		cli.StringFlag{
+			Name:  SSHKeyPath,
+			Value: sshKeyPath,
+			Usage: "Specify node ssh key path",
+		},
+		cli.StringFlag{
+			Name:  Port,
+			Usage: "Specify node ssh port, default to 22",
+		},
+		cli.StringFlag{
+			Name:  InternalAddress,
+			Usage: "Specify node internal address used for components communication",
+		},
+		cli.StringFlag{
+			Name:  HostnameOverride,
+			Usage: "Specify node hostname override",
+		},
+		cli.StringFlag{
+			Name:  DockerSocket,
+			Usage: "Specify node docker socket, default to /var/run/docker.sock",
+		},
+		cli.BoolFlag{
+			Name:  SSHAgentAuth,
+			Usage: "Use the ssh agent of the local host to authenticate",
+		},
+		cli.StringFlag{
+			Name:  SSHKey,
+			Usage: "Specify node ssh private key content, '@<file>' reads it from the file",
+		},
+		cli.StringSliceFlag{
+			Name:  Label,
+			Usage: "Specify node label in key=value format, can be repeated",
+		},
+	}
+}
+
+// applyNodeFlags sets the node fields from the flags, when all is false
+// only the flags given on the command line are applied.
+func applyNodeFlags(ctx *cli.Context, node *v3.RKEConfigNode, all bool) error {
+	isSet := func(name string) bool {
+		return all || ctx.IsSet(name)
+	}
+
+	if isSet(Roles) {
+		roles, err := util.ParseRoles(ctx.String(Roles))
+		if err != nil {
+			return err
+		}
+		node.Role = roles
+	}
+	if isSet(User) {
+		node.User = ctx.String(User)
+	}
+	if isSet(SSHKeyPath) {
+		node.SSHKeyPath = ctx.String(SSHKeyPath)
+	}
+	if isSet(Port) {
+		node.Port = ctx.String(Port)
+	}
+	if isSet(InternalAddress) {
+		node.InternalAddress = ctx.String(InternalAddress)
+	}
+	if isSet(HostnameOverride) {
+		node.HostnameOverride = ctx.String(HostnameOverride)
+	}
+	if isSet(DockerSocket) {
+		node.DockerSocket = ctx.String(DockerSocket)
+	}
+	if isSet(SSHAgentAuth) {
+		node.SSHAgentAuth = ctx.Bool(SSHAgentAuth)
+	}
+	if isSet(SSHKey) {
+		sshKey := ctx.String(SSHKey)
+		if strings.HasPrefix(sshKey, "@") {
+			bytes, err := ioutil.ReadFile(sshKey[1:])
+			if err != nil {
+				return fmt.Errorf("can not read ssh key %s: %v", sshKey[1:], err)
+			}
+			sshKey = string(bytes)
+		}
+		node.SSHKey = sshKey
+	}
+	if isSet(Label) {
+		labels, err := util.ParseLabels(ctx.StringSlice(Label))
+		if err != nil {
+			return err
+		}
+		if node.Labels == nil {
+			node.Labels = map[string]string{}
+		}
+		for key, value := range labels {
+			node.Labels[key] = value
+		}
+	}
+
+	return nil
+}
+
+func nodeLs(ctx *cli.Context) error {
+	cubeCtx, err := currentContext(ctx)
 	if err != nil {
-		logrus.Errorf("%v", err)
 		return err
 	}
 
-	// retrieve form kubernetes backend
-	client := k8s.NewClientGenerator(KubeConfigLocation)
-	nodes, err := client.Clientset.CoreV1().Nodes().List(util.ListEverything)
+	config, err := readRKEConfig(cubeCtx.RKEConfig)
 	if err != nil {
-		logrus.Errorf("can not retrieve kubernetes nodes: %v", err)
+		logrus.Errorf("%v", err)
 		return err
 	}
 
-	// whether rke config file nodes match kubernetes nodes or not
-	isSyncMap := map[string]bool{}
-	for _, node := range nodes.Items {
-		for _, address := range node.Status.Addresses {
-			if address.Type == v1.NodeInternalIP {
-				isSyncMap[address.Address] = true
-				break
-			}
-		}
+	// retrieve form kubernetes backend, before `cube rke up` there is no
+	// cluster to ask so only the rke config is listed
+	nodes, err := listKubernetesNodes(cubeCtx.KubeConfig, ctx.Duration(Timeout))
+	reachable := err == nil
+	if !reachable {
+		logrus.Warnf("cube node ls: can not retrieve kubernetes nodes, showing the rke config only: %v", err)
+		nodes = &v1.NodeList{}
 	}
 
 	writer := table.NewNodeWriter([][]string{
-		{"ADDRESS", "{{.Config.Address}}"},
-		{"ROLE", "{{.Config.Role}}"},
+		{"ADDRESS", "{{.Config.Address}}", table.PresetNarrow},
+		{"ROLE", "{{.Config.Role}}", table.PresetNarrow},
+		{"INTERNAL ADDRESS", "{{.Config.InternalAddress}}", table.PresetWide},
+		{"HOSTNAME OVERRIDE", "{{.Config.HostnameOverride}}", table.PresetWide},
 		{"USER", "{{.Config.User}}"},
 		{"SSH KEY PATH", "{{.Config.SSHKeyPath}}"},
-		{"SYNC", "{{.Sync}}"},
+		{"SYNC", "{{.State}}", table.PresetNarrow},
+		{"READY", "{{.Status.Ready}}", table.PresetNarrow},
+		{"VERSION", "{{.Status.KubeletVersion}}"},
+		{"OS IMAGE", "{{.Status.OSImage}}"},
+		{"CONTAINER RUNTIME", "{{.Status.ContainerRuntime}}"},
+		{"CPU", "{{.Status.CPU}}", "", "{{.Status.CPU | quantity}}"},
+		{"MEMORY", "{{.Status.Memory}}", "", "{{.Status.Memory | quantity}}"},
+		{"AGE", "{{.Status.Created | age}}", "", "{{.Status.Created | seconds}}"},
+		{"TAINTS", "{{.Status.Taints | join}}"},
+		{"LABELS", "{{.Config.Labels | labels}}", table.PresetWide},
 	}, ctx)
-	defer writer.Close()
+	collectOutput(writer)
 
+	// whether rke config file nodes match kubernetes nodes or not
+	matched := map[string]bool{}
 	for _, node := range config.Nodes {
-		if _, ok := isSyncMap[node.Address]; ok {
-			output := &NodeOutput{
-				Config: node,
-				Sync:   true,
+		output := NodeOutput{
+			Config: util.WithoutSecrets(node),
+			State:  NodeStatePending,
+		}
+		if !reachable {
+			output.State = NodeStateUnknown
+		}
+		for _, k8sNode := range nodes.Items {
+			if matchKubernetesNode(node, k8sNode) {
+				matched[k8sNode.Name] = true
+				output.Sync = true
+				output.State = NodeStateSynced
+				output.Status = kubernetesNodeStatus(k8sNode)
+				break
 			}
-			writer.Write(*output)
+		}
+		writer.Write(output)
+	}
+
+	for _, k8sNode := range nodes.Items {
+		if matched[k8sNode.Name] {
+			continue
+		}
+		writer.Write(NodeOutput{
+			Config: v3.RKEConfigNode{
+				Address: kubernetesNodeAddress(k8sNode),
+				Role:    kubernetesNodeRoles(k8sNode),
+			},
+			State:  NodeStateUnmanaged,
+			Status: kubernetesNodeStatus(k8sNode),
+		})
+	}
+
+	return writer.Close()
+}
+
+func listKubernetesNodes(kubeConfig string, timeout time.Duration) (*v1.NodeList, error) {
+	client, err := k8s.NewClientGenerator(kubeConfig, timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	return client.Clientset.CoreV1().Nodes().List(util.ListEverything)
+}
+
+// matchKubernetesNode reports whether the kubernetes node was provisioned from
+// the rke config node, rke names nodes after the hostname override or the address.
+func matchKubernetesNode(node v3.RKEConfigNode, k8sNode v1.Node) bool {
+	if k8sNode.Name == node.Address || (node.HostnameOverride != "" && k8sNode.Name == node.HostnameOverride) {
+		return true
+	}
+
+	for _, address := range k8sNode.Status.Addresses {
+		if address.Type != v1.NodeInternalIP && address.Type != v1.NodeExternalIP {
+			continue
+		}
+		if address.Address == node.Address || (node.InternalAddress != "" && address.Address == node.InternalAddress) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func kubernetesNodeAddress(k8sNode v1.Node) string {
+	for _, address := range k8sNode.Status.Addresses {
+		if address.Type == v1.NodeInternalIP {
+			return address.Address
+		}
+	}
+	return k8sNode.Name
+}
+
+func kubernetesNodeRoles(k8sNode v1.Node) []string {
+	roles := []string{}
+	for _, role := range util.NodeRoles {
+		if _, ok := k8sNode.Labels[nodeRoleLabelPrefix+role]; ok {
+			roles = append(roles, role)
+		}
+	}
+	return roles
+}
+
+func kubernetesNodeStatus(k8sNode v1.Node) NodeStatus {
+	status := NodeStatus{
+		Name:             k8sNode.Name,
+		Ready:            string(v1.ConditionUnknown),
+		KubeletVersion:   k8sNode.Status.NodeInfo.KubeletVersion,
+		OSImage:          k8sNode.Status.NodeInfo.OSImage,
+		ContainerRuntime: k8sNode.Status.NodeInfo.ContainerRuntimeVersion,
+	}
+	if !k8sNode.CreationTimestamp.IsZero() {
+		status.Created = &k8sNode.CreationTimestamp.Time
+	}
+
+	for _, condition := range k8sNode.Status.Conditions {
+		if condition.Type == v1.NodeReady {
+			status.Ready = string(condition.Status)
+			break
+		}
+	}
+
+	if cpu, ok := k8sNode.Status.Allocatable[v1.ResourceCPU]; ok {
+		status.CPU = cpu.String()
+	}
+	if memory, ok := k8sNode.Status.Allocatable[v1.ResourceMemory]; ok {
+		status.Memory = memory.String()
+	}
+
+	for _, taint := range k8sNode.Spec.Taints {
+		if taint.Value == "" {
+			status.Taints = append(status.Taints, taint.Key+":"+string(taint.Effect))
 		} else {
-			output := &NodeOutput{
-				Config: node,
-				Sync:   false,
-			}
-			writer.Write(*output)
+			status.Taints = append(status.Taints, taint.Key+"="+taint.Value+":"+string(taint.Effect))
 		}
 	}
 
-	return writer.Err()
+	return status
 }
 
 func nodeAdd(ctx *cli.Context) error {
 	args := ctx.Args()
 	if len(args) < 1 {
-		return fmt.Errorf("cube node add: no arguments")
+		return usageErrorf("cube node add: no arguments")
 	}
 
 	address := args[0]
 	if "" == address {
-		return fmt.Errorf("cube node add: require %v", Address)
+		return usageErrorf("cube node add: require %v", Address)
+	}
+	addTarget(address)
+
+	newNode := v3.RKEConfigNode{
+		Address: address,
+	}
+	if err := applyNodeFlags(ctx, &newNode, true); err != nil {
+		return usageErrorf("cube node add: %v", err)
+	}
+	if err := util.ValidateNode(newNode); err != nil {
+		return usageErrorf("cube node add: %v", err)
+	}
+
+	cubeCtx, err := currentContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	added := false
+	err = updateRKEConfig(cubeCtx.RKEConfig, func(config *v3.RancherKubernetesEngineConfig) error {
+		if config.Nodes != nil && len(config.Nodes) > 0 {
+			for _, node := range config.Nodes {
+				if node.Address == address {
+					logrus.Warnf("cube node add: node already exist")
+					return errSkipWrite
+				}
+			}
+		}
+
+		config.Nodes = append(config.Nodes, newNode)
+		added = true
+
+		for _, warning := range util.CheckClusterNodes(config.Nodes) {
+			logrus.Warnf("cube node add: %s", warning)
+		}
+		return nil
+	})
+	if err != nil {
+		logrus.Errorf("cube node add: write rke config file error %v", err)
+		return err
 	}
 
-	roles := ctx.String(Roles)
-	rolesList := strings.Split(roles, ",")
+	if added {
+		addChange("node %s added", address)
+	}
+	return nil
+}
+
+func nodeUpdate(ctx *cli.Context) error {
+	args := ctx.Args()
+	if len(args) < 1 {
+		return usageErrorf("cube node update: no arguments")
+	}
 
-	user := ctx.String(User)
-	sshKeyPath := ctx.String(SSHKeyPath)
+	address := args[0]
+	if "" == address {
+		return usageErrorf("cube node update: require %v", Address)
+	}
+	addTarget(address)
 
-	config := &v3.RancherKubernetesEngineConfig{}
+	var addRoles, removeRoles []string
 	var err error
+	if ctx.IsSet(AddRole) {
+		if addRoles, err = util.ParseRoles(ctx.String(AddRole)); err != nil {
+			return usageErrorf("cube node update: %v", err)
+		}
+	}
+	if ctx.IsSet(RemoveRole) {
+		if removeRoles, err = util.ParseRoles(ctx.String(RemoveRole)); err != nil {
+			return usageErrorf("cube node update: %v", err)
+		}
+	}
 
-	if _, fErr := os.Stat(RKEConfigDefault); fErr != nil {
-		config, err = util.ReadRKEConfig(RKEBaseConfigDefault)
-	} else {
-		config, err = util.ReadRKEConfig(RKEConfigDefault)
+	cubeCtx, err := currentContext(ctx)
+	if err != nil {
+		return err
 	}
 
+	err = updateRKEConfig(cubeCtx.RKEConfig, func(config *v3.RancherKubernetesEngineConfig) error {
+		index := util.FindNode(config.Nodes, address)
+		if index < 0 {
+			return notFoundErrorf("cube node update: node %s not found", address)
+		}
+
+		// work on a copy so that a failed validation leaves the config untouched
+		node := config.Nodes[index]
+		node.Role = append([]string{}, node.Role...)
+		labels := map[string]string{}
+		for key, value := range node.Labels {
+			labels[key] = value
+		}
+		node.Labels = labels
+
+		if err := applyNodeFlags(ctx, &node, false); err != nil {
+			return usageErrorf("cube node update: %v", err)
+		}
+
+		for _, role := range addRoles {
+			if !util.NodeHasRole(node, role) {
+				node.Role = append(node.Role, role)
+			}
+		}
+		for _, role := range removeRoles {
+			node.Role = util.RemoveString(node.Role, role)
+		}
+		for _, key := range ctx.StringSlice(Unlabel) {
+			delete(node.Labels, key)
+		}
+
+		if err := util.ValidateNodeChanges(config.Nodes[index], node); err != nil {
+			return usageErrorf("cube node update: %v", err)
+		}
+
+		config.Nodes[index] = node
+
+		for _, warning := range util.CheckClusterNodes(config.Nodes) {
+			logrus.Warnf("cube node update: %s", warning)
+		}
+		return nil
+	})
 	if err != nil {
-		logrus.Errorf("%v", err)
 		return err
 	}
 
-	if config.Nodes != nil && len(config.Nodes) > 0 {
-		for _, node := range config.Nodes {
-			if node.Address == address {
-				logrus.Warnf("cube node add: node already exist")
-				return nil
+	addChange("node %s updated", address)
+	return nil
+}
+
+// mergeImportedNodes adds the new nodes to the config and updates the existing
+// ones, the new nodes take the user and ssh key path of defaults when they have
+// none. The new nodes and the changed fields of the updated ones are validated
+// before the config is changed.
+func mergeImportedNodes(config *v3.RancherKubernetesEngineConfig, imported []v3.RKEConfigNode, defaults v3.RKEConfigNode) (int, int, error) {
+	nodes := append([]v3.RKEConfigNode{}, config.Nodes...)
+	added, updated := 0, 0
+
+	for _, node := range imported {
+		if index := util.FindNode(nodes, node.Address); index >= 0 {
+			merged := util.MergeNode(nodes[index], node)
+			if err := util.ValidateNodeChanges(nodes[index], merged); err != nil {
+				return 0, 0, fmt.Errorf("node %s: %v", node.Address, err)
 			}
+			nodes[index] = merged
+			updated++
+			continue
+		}
+
+		if node.User == "" {
+			node.User = defaults.User
+		}
+		if node.SSHKeyPath == "" && node.SSHKey == "" && !node.SSHAgentAuth {
+			node.SSHKeyPath = defaults.SSHKeyPath
+		}
+		if err := util.ValidateNode(node); err != nil {
+			return 0, 0, fmt.Errorf("node %s: %v", node.Address, err)
 		}
+		nodes = append(nodes, node)
+		added++
+	}
+
+	config.Nodes = nodes
+	return added, updated, nil
+}
+
+func nodeImport(ctx *cli.Context) error {
+	args := ctx.Args()
+	if len(args) < 1 {
+		return usageErrorf("cube node import: no arguments")
+	}
+	filename := args[0]
+	addTarget(filename)
+
+	format := ctx.String(InventoryFormat)
+	if format == "" {
+		format = util.InventoryFormat(filename)
+	}
+
+	data, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return notFoundErrorf("cube node import: %v", err)
+	} else if err != nil {
+		return fmt.Errorf("cube node import: %v", err)
+	}
+
+	imported, err := util.ParseInventory(data, format)
+	if err != nil {
+		return usageErrorf("cube node import: %v", err)
+	}
+
+	defaults := v3.RKEConfigNode{
+		User:       ctx.String(User),
+		SSHKeyPath: ctx.String(SSHKeyPath),
 	}
 
-	config.Nodes = append(config.Nodes, v3.RKEConfigNode{
-		Address:    address,
-		Role:       rolesList,
-		User:       user,
-		SSHKeyPath: sshKeyPath,
+	cubeCtx, err := currentContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	if ctx.Bool(DryRun) {
+		config, err := readRKEConfig(cubeCtx.RKEConfig)
+		if err != nil {
+			return err
+		}
+		before, err := yaml.Marshal(config)
+		if err != nil {
+			return err
+		}
+
+		added, updated, err := mergeImportedNodes(config, imported, defaults)
+		if err != nil {
+			return usageErrorf("cube node import: %v", err)
+		}
+		after, err := yaml.Marshal(config)
+		if err != nil {
+			return err
+		}
+
+		diff := util.LineDiff(cubeCtx.RKEConfig, cubeCtx.RKEConfig+" (imported)", string(before), string(after))
+		if structuredOutput() {
+			setData(diff)
+		} else {
+			fmt.Print(diff)
+		}
+		logrus.Infof("cube node import: %d nodes would be added, %d nodes would be updated", added, updated)
+		return nil
+	}
+
+	added, updated := 0, 0
+	err = updateRKEConfig(cubeCtx.RKEConfig, func(config *v3.RancherKubernetesEngineConfig) error {
+		added, updated, err = mergeImportedNodes(config, imported, defaults)
+		if err != nil {
+			return usageErrorf("cube node import: %v", err)
+		}
+
+		for _, warning := range util.CheckClusterNodes(config.Nodes) {
+			logrus.Warnf("cube node import: %s", warning)
+		}
+		return nil
 	})
+	if err != nil {
+		return err
+	}
 
-	err = util.WriteRKEConfig(config, RKEConfigDefault)
+	addChange("%d nodes added, %d nodes updated", added, updated)
+	return nil
+}
+
+func nodeExport(ctx *cli.Context) error {
+	cubeCtx, err := currentContext(ctx)
 	if err != nil {
-		logrus.Errorf("cube node add: write rke config file error %v", err)
 		return err
 	}
 
+	config, err := readRKEConfig(cubeCtx.RKEConfig)
+	if err != nil {
+		return err
+	}
+
+	data, err := util.FormatInventory(config.Nodes, ctx.String(InventoryFormat))
+	if err != nil {
+		return usageErrorf("cube node export: %v", err)
+	}
+
+	if len(ctx.Args()) > 0 {
+		filename := ctx.Args()[0]
+		addTarget(filename)
+		if err := ioutil.WriteFile(filename, data, 0600); err != nil {
+			return err
+		}
+		addChange("%d nodes exported to %s", len(config.Nodes), filename)
+		return nil
+	}
+
+	if structuredOutput() {
+		setData(string(data))
+		return nil
+	}
+	_, err = os.Stdout.Write(data)
 	return err
 }
 
 func nodeRm(ctx *cli.Context) error {
-	args := ctx.Args()
-	if len(args) < 1 {
-		return fmt.Errorf("cube node remove: no arguments")
+	addresses := []string{}
+	for _, address := range ctx.Args() {
+		if address != "" {
+			addresses = append(addresses, address)
+		}
+	}
+	selector, err := labels.Parse(ctx.String(Selector))
+	if err != nil {
+		return usageErrorf("cube node remove: invalid %s: %v", Selector, err)
+	}
+	if len(addresses) == 0 && selector.Empty() {
+		return usageErrorf("cube node remove: require %v or %s", Address, Selector)
 	}
 
-	address := args[0]
-	if "" == address {
-		return fmt.Errorf("cube node remove: require %v", Address)
+	cubeCtx, err := currentContext(ctx)
+	if err != nil {
+		return err
 	}
 
-	config := &v3.RancherKubernetesEngineConfig{}
-	var err error
+	// check before draining so that a refused removal leaves the cluster untouched
+	config, err := readRKEConfig(cubeCtx.RKEConfig)
+	if err != nil {
+		return err
+	}
+	removed, err := selectNodes(config.Nodes, addresses, selector)
+	if err != nil {
+		return prefixError("cube node remove", err)
+	}
+	if err := checkNodeRemoval(config.Nodes, removed, ctx.Bool(Force)); err != nil {
+		return prefixError("cube node remove", err)
+	}
+	for _, node := range removed {
+		addTarget(node.Address)
+	}
 
-	if _, fErr := os.Stat(RKEConfigDefault); fErr != nil {
-		config, err = util.ReadRKEConfig(RKEBaseConfigDefault)
-	} else {
-		config, err = util.ReadRKEConfig(RKEConfigDefault)
+	if ctx.Bool(Drain) {
+		if err := drainKubernetesNodes(ctx, cubeCtx, removed); err != nil {
+			return prefixError("cube node remove", err)
+		}
 	}
 
+	err = updateRKEConfig(cubeCtx.RKEConfig, func(config *v3.RancherKubernetesEngineConfig) error {
+		// select again under the lock, the config may have changed meanwhile
+		removed, err = selectNodes(config.Nodes, addresses, selector)
+		if err != nil {
+			return err
+		}
+		if err := checkNodeRemoval(config.Nodes, removed, ctx.Bool(Force)); err != nil {
+			return err
+		}
+
+		left := []v3.RKEConfigNode{}
+		for _, node := range config.Nodes {
+			if util.FindNode(removed, node.Address) < 0 {
+				left = append(left, node)
+			}
+		}
+		config.Nodes = left
+		return nil
+	})
 	if err != nil {
-		logrus.Errorf("%v", err)
+		logrus.Errorf("cube node remove: write rke config error %v", err)
 		return err
 	}
+	for _, node := range removed {
+		addChange("node %s removed", node.Address)
+	}
 
-	foundIndex := -1
-	for index, node := range config.Nodes {
-		if node.Address == address {
-			foundIndex = index
-			break
+	if err := deleteKubernetesNodes(ctx, cubeCtx, removed); err != nil {
+		return prefixError("cube node remove", err)
+	}
+
+	// the kubelets run until rke up removes them from the hosts
+	logrus.Infof("cube node remove: run 'cube rke up' to remove the nodes from the kubernetes cluster")
+	return nil
+}
+
+// selectNodes returns the nodes of the addresses and the nodes matching the
+// label selector, every address must be in the config.
+func selectNodes(nodes []v3.RKEConfigNode, addresses []string, selector labels.Selector) ([]v3.RKEConfigNode, error) {
+	selected := []v3.RKEConfigNode{}
+	for _, address := range addresses {
+		index := util.FindNode(nodes, address)
+		if index < 0 {
+			return nil, notFoundErrorf("node %s not found", address)
+		}
+		if util.FindNode(selected, address) < 0 {
+			selected = append(selected, nodes[index])
+		}
+	}
+
+	if !selector.Empty() {
+		matched := 0
+		for _, node := range nodes {
+			if !selector.Matches(labels.Set(node.Labels)) {
+				continue
+			}
+			matched++
+			if util.FindNode(selected, node.Address) < 0 {
+				selected = append(selected, node)
+			}
+		}
+		if matched == 0 {
+			return nil, notFoundErrorf("no node matches %s %s", Selector, selector.String())
 		}
 	}
 
-	if config.Nodes == nil || len(config.Nodes) <= 0 {
-		logrus.Warnf("cube node remove: no nodes in config file")
+	return selected, nil
+}
+
+// checkNodeRemoval refuses to remove the last etcd or controlplane node, rke
+// cannot bring the cluster up without them.
+func checkNodeRemoval(nodes, removed []v3.RKEConfigNode, force bool) error {
+	if force {
 		return nil
 	}
 
-	if len(config.Nodes) == 1 {
-		config.Nodes = []v3.RKEConfigNode{}
-	} else {
-		left := config.Nodes[:foundIndex]
-		right := config.Nodes[foundIndex+1:]
-		config.Nodes = util.MergeNodes(left, right)
+	for _, role := range []string{services.ETCDRole, services.ControlRole} {
+		if util.CountRole(nodes, role) > 0 && util.CountRole(nodes, role) == util.CountRole(removed, role) {
+			return conflictErrorf("refuse to remove the last %s node, use --%s to remove it anyway", role, Force)
+		}
 	}
+	return nil
+}
 
-	err = util.WriteRKEConfig(config, RKEConfigDefault)
+// drainKubernetesNodes cordons and drains the kubernetes nodes of the rke
+// nodes, the nodes which never joined the cluster are skipped.
+func drainKubernetesNodes(ctx *cli.Context, cubeCtx *util.Context, nodes []v3.RKEConfigNode) error {
+	client, err := k8s.NewClientGenerator(cubeCtx.KubeConfig, ctx.Duration(Timeout))
 	if err != nil {
-		logrus.Errorf("cube node remove: write rke config error %v", err)
 		return err
 	}
 
-	return err
+	names, err := kubernetesNodeNames(client, nodes)
+	if err != nil {
+		return err
+	}
+
+	for _, node := range nodes {
+		name := names[node.Address]
+		if name == "" {
+			logrus.Warnf("cube node remove: node %s is not in kubernetes, skip draining", node.Address)
+			continue
+		}
+
+		logrus.Infof("cube node remove: cordon kubernetes node %s", name)
+		if err := client.CordonNode(name); err != nil {
+			return err
+		}
+
+		logrus.Infof("cube node remove: drain kubernetes node %s", name)
+		err = client.DrainNode(name, k8s.DrainOptions{
+			DeleteUnmanaged: ctx.Bool(DeleteUnmanaged),
+			DeleteEmptyDir:  ctx.Bool(DeleteEmptyDir),
+			GracePeriod:     ctx.Int(GracePeriod),
+			Timeout:         ctx.Duration(DrainTimeout),
+		})
+		if err != nil {
+			return err
+		}
+		addChange("kubernetes node %s drained", name)
+	}
+
+	return nil
+}
+
+// deleteKubernetesNodes deletes the kubernetes nodes of the removed rke nodes,
+// nothing is deleted when the cluster was never brought up.
+func deleteKubernetesNodes(ctx *cli.Context, cubeCtx *util.Context, nodes []v3.RKEConfigNode) error {
+	if _, err := os.Stat(cubeCtx.KubeConfig); os.IsNotExist(err) {
+		logrus.Debugf("cube node remove: kubernetes config %s not found, no kubernetes node to delete", cubeCtx.KubeConfig)
+		return nil
+	}
+
+	client, err := k8s.NewClientGenerator(cubeCtx.KubeConfig, ctx.Duration(Timeout))
+	if err != nil {
+		return err
+	}
+	names, err := kubernetesNodeNames(client, nodes)
+	if err != nil {
+		return err
+	}
+
+	for _, node := range nodes {
+		name := names[node.Address]
+		if name == "" {
+			logrus.Debugf("cube node remove: node %s is not in kubernetes, nothing to delete", node.Address)
+			continue
+		}
+		if err := client.DeleteNode(name); err != nil {
+			return errors.Wrapf(err, "delete kubernetes node %s", name)
+		}
+		addChange("kubernetes node %s deleted", name)
+	}
+	return nil
+}
+
+// kubernetesNodeNames returns the names of the kubernetes nodes keyed by the
+// address of the rke nodes, the nodes which never joined the cluster are left
+// out.
+func kubernetesNodeNames(client *k8s.ClientGenerator, nodes []v3.RKEConfigNode) (map[string]string, error) {
+	k8sNodes, err := client.Clientset.CoreV1().Nodes().List(util.ListEverything)
+	if err != nil {
+		return nil, err
+	}
+
+	names := map[string]string{}
+	for _, node := range nodes {
+		for _, k8sNode := range k8sNodes.Items {
+			if matchKubernetesNode(node, k8sNode) {
+				names[node.Address] = k8sNode.Name
+				break
+			}
+		}
+	}
+	return names, nil
 }
@@ -0,0 +1,207 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+
+	"github.com/cnrancher/cube-cli/cmd/pkg/table"
+	"github.com/cnrancher/cube-cli/docker"
+	"github.com/cnrancher/cube-cli/util"
+
+	"github.com/docker/docker/client"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	OutputFlag = "output"
+	OutputJSON = "json"
+	OutputYAML = "yaml"
+)
+
+// The exit codes are part of the cli interface, scripts rely on them.
+const (
+	ExitOK          = 0
+	ExitError       = 1
+	ExitUsage       = 2
+	ExitNotFound    = 3
+	ExitConflict    = 4
+	ExitUnavailable = 5
+)
+
+// Result is the outcome of a command printed with the global --output flag.
+type Result struct {
+	Action  string      `json:"action" yaml:"action"`
+	Target  []string    `json:"target" yaml:"target"`
+	Changes []string    `json:"changes" yaml:"changes"`
+	Code    int         `json:"code" yaml:"code"`
+	Message string      `json:"message" yaml:"message"`
+	Data    interface{} `json:"data,omitempty" yaml:"data,omitempty"`
+}
+
+var (
+	outputFormat  string
+	commandResult = &Result{Target: []string{}, Changes: []string{}}
+	resultWriter  *table.Writer
+	resultWritten bool
+	actionStarted bool
+)
+
+// SetOutput selects the format of the command result, an empty format keeps
+// the plain command output.
+func SetOutput(format string) error {
+	switch format {
+	case "", OutputJSON, OutputYAML:
+		outputFormat = format
+		return nil
+	}
+	return usageErrorf("invalid %s %s, must be %s or %s", OutputFlag, format, OutputJSON, OutputYAML)
+}
+
+// structuredOutput reports whether the command prints its result instead of
+// its plain output.
+func structuredOutput() bool {
+	return outputFormat != ""
+}
+
+// startAction names the result after the command being run.
+func startAction(ctx *cli.Context) {
+	actionStarted = true
+	commandResult.Action = ctx.Command.FullName()
+}
+
+// addTarget records what the command acts on.
+func addTarget(targets ...string) {
+	commandResult.Target = append(commandResult.Target, targets...)
+}
+
+// addChange logs a change made by the command and records it in the result.
+func addChange(format string, args ...interface{}) {
+	change := fmt.Sprintf(format, args...)
+	logrus.Info(change)
+	commandResult.Changes = append(commandResult.Changes, change)
+}
+
+// setData records the output of the command, it is printed within the result.
+func setData(data interface{}) {
+	commandResult.Data = data
+}
+
+// collectOutput makes the table writer keep its rows for the result.
+func collectOutput(writer *table.Writer) {
+	if structuredOutput() {
+		writer.Collect()
+		resultWriter = writer
+	}
+}
+
+func writeResult(err error) error {
+	resultWritten = true
+	commandResult.Code = ExitCode(err)
+	if err != nil {
+		commandResult.Message = err.Error()
+	}
+	if commandResult.Data == nil && resultWriter != nil {
+		commandResult.Data = resultWriter.Items()
+	}
+
+	var content []byte
+	var marshalErr error
+	if outputFormat == OutputYAML {
+		content, marshalErr = yaml.Marshal(commandResult)
+	} else {
+		content, marshalErr = json.MarshalIndent(commandResult, "", "    ")
+		content = append(content, '\n')
+	}
+	if marshalErr != nil {
+		return marshalErr
+	}
+
+	_, writeErr := os.Stdout.Write(content)
+	return writeErr
+}
+
+// Exit ends cube with the exit code of the error. The errors raised before
+// any command runs come from the flags and are usage errors.
+func Exit(err error) {
+	if !actionStarted && ExitCode(err) == ExitError {
+		err = &CommandError{code: ExitUsage, err: err}
+	}
+	if structuredOutput() && !resultWritten {
+		if writeErr := writeResult(err); writeErr != nil {
+			logrus.Errorf("write result error: %v", writeErr)
+		}
+	}
+
+	logrus.Error(err)
+	os.Exit(ExitCode(err))
+}
+
+// CommandError is a command failure along with its exit code.
+type CommandError struct {
+	code int
+	err  error
+}
+
+func (e *CommandError) Error() string {
+	return e.err.Error()
+}
+
+// Code returns the exit code of the failure. It is not named ExitCode, the cli
+// package would exit right away on such errors.
+func (e *CommandError) Code() int {
+	return e.code
+}
+
+func usageErrorf(format string, args ...interface{}) error {
+	return &CommandError{code: ExitUsage, err: fmt.Errorf(format, args...)}
+}
+
+func notFoundErrorf(format string, args ...interface{}) error {
+	return &CommandError{code: ExitNotFound, err: fmt.Errorf(format, args...)}
+}
+
+func conflictErrorf(format string, args ...interface{}) error {
+	return &CommandError{code: ExitConflict, err: fmt.Errorf(format, args...)}
+}
+
+func unavailableErrorf(format string, args ...interface{}) error {
+	return &CommandError{code: ExitUnavailable, err: fmt.Errorf(format, args...)}
+}
+
+// prefixError prefixes the error message with the command, keeping its exit code.
+func prefixError(prefix string, err error) error {
+	return &CommandError{code: ExitCode(err), err: fmt.Errorf("%s: %v", prefix, err)}
+}
+
+// ExitCode returns the exit code of the command error.
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+
+	cause := errors.Cause(err)
+	if client.IsErrConnectionFailed(cause) {
+		return ExitUnavailable
+	}
+	switch cause := cause.(type) {
+	case *CommandError:
+		return cause.code
+	case *docker.NotFoundError:
+		return ExitNotFound
+	case *docker.NotManagedError:
+		return ExitConflict
+	case *util.RevisionNotFoundError:
+		return ExitNotFound
+	case *table.FlagError:
+		return ExitUsage
+	case *url.Error, net.Error:
+		return ExitUnavailable
+	}
+	return ExitError
+}
@@ -0,0 +1,242 @@
+package table
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/urfave/cli"
+)
+
+// The column presets selected by the format flag. A narrow column shows with
+// every preset, a wide one only with the wide preset, the others with the
+// default and the wide presets.
+const (
+	PresetNarrow = "narrow"
+	PresetWide   = "wide"
+)
+
+// FlagError reports an invalid column, filter or sort flag.
+type FlagError struct {
+	err error
+}
+
+func (e *FlagError) Error() string {
+	return e.err.Error()
+}
+
+type column struct {
+	header   string
+	template string
+	preset   string
+	// sortKey renders the value the column is sorted on, when the displayed
+	// value does not sort, e.g. an age or a memory size
+	sortKey string
+}
+
+type filter struct {
+	column int
+	value  string
+	// negate keeps the rows which do not match the value
+	negate bool
+}
+
+type row struct {
+	obj  interface{}
+	text string
+	key  string
+}
+
+// parseColumns reads the {header, template[, preset[, sort key]]} column
+// definitions.
+func parseColumns(values [][]string) []column {
+	columns := make([]column, 0, len(values))
+	for _, v := range values {
+		c := column{header: v[0], template: columnTemplate(v[1])}
+		if len(v) > 2 {
+			c.preset = v[2]
+		}
+		if len(v) > 3 {
+			c.sortKey = columnTemplate(v[3])
+		}
+		columns = append(columns, c)
+	}
+	return columns
+}
+
+// columnTemplate makes a template of a bare field name.
+func columnTemplate(template string) string {
+	if !strings.Contains(template, "{{") {
+		return "{{." + template + "}}"
+	}
+	return template
+}
+
+// columnName returns the name a column is referred to by the flags, e.g.
+// ssh-key-path for the SSH KEY PATH column.
+func columnName(header string) string {
+	return strings.Replace(strings.Replace(strings.ToLower(header), " ", "-", -1), "_", "-", -1)
+}
+
+func (w *Writer) findColumn(name string) (int, error) {
+	names := []string{}
+	for i, c := range w.columns {
+		if columnName(c.header) == columnName(strings.TrimSpace(name)) {
+			return i, nil
+		}
+		names = append(names, columnName(c.header))
+	}
+	return -1, fmt.Errorf("unknown column %s, must be one of %s", name, strings.Join(names, ", "))
+}
+
+// selectColumns returns the columns given by the columns flag, or else the
+// columns of the preset.
+func (w *Writer) selectColumns(names, preset string) ([]column, error) {
+	selected := []column{}
+	if names != "" {
+		for _, name := range strings.Split(names, ",") {
+			i, err := w.findColumn(name)
+			if err != nil {
+				return nil, err
+			}
+			selected = append(selected, w.columns[i])
+		}
+		return selected, nil
+	}
+
+	for _, c := range w.columns {
+		switch {
+		case preset == PresetWide,
+			preset == PresetNarrow && c.preset == PresetNarrow,
+			preset == "" && c.preset != PresetWide:
+			selected = append(selected, c)
+		}
+	}
+	if len(selected) == 0 {
+		return w.selectColumns(names, "")
+	}
+	return selected, nil
+}
+
+// setFilters parses the column=value and column!=value filters.
+func (w *Writer) setFilters(values []string) error {
+	for _, value := range values {
+		parts := strings.SplitN(value, "=", 2)
+		negate := len(parts) == 2 && strings.HasSuffix(parts[0], "!")
+		if negate {
+			parts[0] = strings.TrimSuffix(parts[0], "!")
+		}
+		if len(parts) != 2 || parts[0] == "" {
+			return fmt.Errorf("invalid filter %s, must be column=value or column!=value", value)
+		}
+		i, err := w.findColumn(parts[0])
+		if err != nil {
+			return err
+		}
+		w.filters = append(w.filters, filter{column: i, value: parts[1], negate: negate})
+	}
+	return nil
+}
+
+// configure applies the column, filter and sort flags.
+func (w *Writer) configure(ctx *cli.Context, preset string) {
+	selected, err := w.selectColumns(ctx.String("columns"), preset)
+	if err != nil {
+		w.err = &FlagError{err}
+		return
+	}
+	values := [][]string{}
+	for _, c := range selected {
+		values = append(values, []string{c.header, c.template})
+	}
+	w.HeaderFormat, w.ValueFormat = SimpleFormat(values)
+
+	if err := w.setFilters(ctx.StringSlice("filter")); err != nil {
+		w.err = &FlagError{err}
+		return
+	}
+
+	if sortBy := ctx.String("sort-by"); sortBy != "" {
+		if w.sortBy, err = w.findColumn(sortBy); err != nil {
+			w.err = &FlagError{err}
+		}
+	}
+}
+
+// cell renders the column of the object as it is displayed.
+func (w *Writer) cell(i int, obj interface{}) (string, error) {
+	buf := bytes.Buffer{}
+	if err := w.printTemplate(&buf, w.columns[i].template, obj); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// sortKey renders the value the object is sorted on.
+func (w *Writer) sortKey(obj interface{}) (string, error) {
+	c := w.columns[w.sortBy]
+	if c.sortKey == "" {
+		return w.cell(w.sortBy, obj)
+	}
+
+	buf := bytes.Buffer{}
+	if err := w.printTemplate(&buf, c.sortKey, obj); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// matches reports whether the object passes every filter, a filter matches
+// the whole cell or one of the values of a list cell, a negated filter none
+// of them.
+func (w *Writer) matches(obj interface{}) (bool, error) {
+	for _, f := range w.filters {
+		cell, err := w.cell(f.column, obj)
+		if err != nil {
+			return false, err
+		}
+		if cellMatches(cell, f.value) == f.negate {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func cellMatches(cell, value string) bool {
+	if strings.EqualFold(cell, value) {
+		return true
+	}
+	for _, field := range strings.FieldsFunc(cell, func(r rune) bool {
+		return r == ' ' || r == ',' || r == '[' || r == ']'
+	}) {
+		if strings.EqualFold(field, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// sortRows orders the rows by the sort keys, the numeric keys come first in
+// numeric order and the other keys follow in string order.
+func sortRows(rows []row) {
+	sort.SliceStable(rows, func(i, j int) bool {
+		a, numericA := parseNumber(rows[i].key)
+		b, numericB := parseNumber(rows[j].key)
+		switch {
+		case numericA && numericB:
+			return a < b
+		case numericA != numericB:
+			return numericA
+		default:
+			return rows[i].key < rows[j].key
+		}
+	})
+}
+
+func parseNumber(s string) (float64, bool) {
+	number, err := strconv.ParseFloat(s, 64)
+	return number, err == nil && !math.IsNaN(number)
+}
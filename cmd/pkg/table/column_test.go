@@ -0,0 +1,213 @@
+package table
+
+import (
+	"flag"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/urfave/cli"
+)
+
+type testNode struct {
+	Name   string
+	Roles  []string
+	Pods   int
+	Memory string
+}
+
+var testNodes = []testNode{
+	{Name: "node1", Roles: []string{"etcd", "controlplane"}, Pods: 10, Memory: "512Mi"},
+	{Name: "node2", Roles: []string{"worker"}, Pods: 9, Memory: "8Gi"},
+	{Name: "Node3", Roles: []string{"controlplane", "worker"}, Pods: 100, Memory: "1Gi"},
+}
+
+var testColumns = [][]string{
+	{"NAME", "{{.Name}}", PresetNarrow},
+	{"ROLE", "{{.Roles}}"},
+	{"PODS", "Pods", PresetWide},
+	{"MEMORY", "{{.Memory}}", "", "{{.Memory | quantity}}"},
+}
+
+// newTestWriter returns a writer of the test columns collecting the rows,
+// set up by the flag arguments.
+func newTestWriter(t *testing.T, args ...string) *Writer {
+	set := flag.NewFlagSet("test", flag.ContinueOnError)
+	for _, f := range withTableFlags(outputServerFlags) {
+		f.Apply(set)
+	}
+	if err := set.Parse(args); err != nil {
+		t.Fatalf("parse %v: %v", args, err)
+	}
+
+	w := newWriter(testColumns, cli.NewContext(nil, set, nil), map[string]interface{}{
+		"quantity": FormatNodeQuantity,
+	}, "")
+	w.Collect()
+	return w
+}
+
+func writeTestNodes(w *Writer) ([]string, error) {
+	for _, node := range testNodes {
+		w.Write(node)
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	names := []string{}
+	for _, item := range w.Items() {
+		names = append(names, item.(testNode).Name)
+	}
+	return names, nil
+}
+
+func TestWriterFilter(t *testing.T) {
+	tests := []struct {
+		name    string
+		filters []string
+		want    []string
+		wantErr string
+	}{
+		{
+			name: "no filter",
+			want: []string{"node1", "node2", "Node3"},
+		},
+		{
+			name:    "equal ignoring case",
+			filters: []string{"name=node3"},
+			want:    []string{"Node3"},
+		},
+		{
+			name:    "equal to a list value",
+			filters: []string{"role=controlplane"},
+			want:    []string{"node1", "Node3"},
+		},
+		{
+			name:    "not equal",
+			filters: []string{"role!=worker"},
+			want:    []string{"node1"},
+		},
+		{
+			name:    "filters combined",
+			filters: []string{"role=controlplane", "name!=node1"},
+			want:    []string{"Node3"},
+		},
+		{
+			name:    "column hidden by the preset",
+			filters: []string{"pods=9"},
+			want:    []string{"node2"},
+		},
+		{
+			name:    "empty value",
+			filters: []string{"role="},
+			want:    []string{},
+		},
+		{
+			name:    "no operator",
+			filters: []string{"role"},
+			wantErr: "invalid filter role, must be column=value or column!=value",
+		},
+		{
+			name:    "no column",
+			filters: []string{"!=worker"},
+			wantErr: "invalid filter !=worker",
+		},
+		{
+			name:    "unknown column",
+			filters: []string{"zone=a"},
+			wantErr: "unknown column zone, must be one of name, role, pods, memory",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			args := []string{}
+			for _, f := range test.filters {
+				args = append(args, "--filter", f)
+			}
+			names, err := writeTestNodes(newTestWriter(t, args...))
+			checkWriter(t, names, err, test.want, test.wantErr)
+		})
+	}
+}
+
+func TestWriterSort(t *testing.T) {
+	tests := []struct {
+		name    string
+		sortBy  string
+		want    []string
+		wantErr string
+	}{
+		{
+			name:   "strings",
+			sortBy: "name",
+			want:   []string{"Node3", "node1", "node2"},
+		},
+		{
+			name:   "numbers",
+			sortBy: "pods",
+			want:   []string{"node2", "node1", "Node3"},
+		},
+		{
+			name:   "sort key",
+			sortBy: "memory",
+			want:   []string{"node1", "Node3", "node2"},
+		},
+		{
+			name:   "list",
+			sortBy: "role",
+			want:   []string{"Node3", "node1", "node2"},
+		},
+		{
+			name:    "unknown column",
+			sortBy:  "zone",
+			wantErr: "unknown column zone",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			names, err := writeTestNodes(newTestWriter(t, "--sort-by", test.sortBy))
+			checkWriter(t, names, err, test.want, test.wantErr)
+		})
+	}
+}
+
+func TestSortRowsMixedKeys(t *testing.T) {
+	keys := []string{"b", "10", "<none>", "9", "NaN", "a", "-1", "9"}
+	want := []string{"-1", "9", "9", "10", "<none>", "NaN", "a", "b"}
+
+	// every starting order gives the same result when the order is consistent
+	for shift := range keys {
+		rows := []row{}
+		for i := range keys {
+			rows = append(rows, row{key: keys[(i+shift)%len(keys)]})
+		}
+		sortRows(rows)
+
+		got := []string{}
+		for _, r := range rows {
+			got = append(got, r.key)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("shift %d: got keys %v, want %v", shift, got, want)
+		}
+	}
+}
+
+func checkWriter(t *testing.T, names []string, err error, want []string, wantErr string) {
+	t.Helper()
+	if wantErr != "" {
+		if _, ok := err.(*FlagError); !ok || !strings.Contains(err.Error(), wantErr) {
+			t.Fatalf("got error %v, want flag error %q", err, wantErr)
+		}
+		return
+	}
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("got rows %v, want %v", names, want)
+	}
+}
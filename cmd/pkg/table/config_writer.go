@@ -0,0 +1,19 @@
+package table
+
+import (
+	"time"
+
+	"github.com/urfave/cli"
+)
+
+func NewConfigWriter(values [][]string, ctx *cli.Context) *Writer {
+	return newWriter(values, ctx, map[string]interface{}{
+		"date": FormatRevisionTimestamp,
+		"json": FormatJSON,
+		"yaml": FormatYAML,
+	}, "{{.Revision}}\n")
+}
+
+func FormatRevisionTimestamp(data time.Time) (string, error) {
+	return data.Local().Format(time.RFC3339), nil
+}
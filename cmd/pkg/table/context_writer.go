@@ -0,0 +1,18 @@
+package table
+
+import "github.com/urfave/cli"
+
+func NewContextWriter(values [][]string, ctx *cli.Context) *Writer {
+	return newWriter(values, ctx, map[string]interface{}{
+		"current": FormatContextCurrent,
+		"json":    FormatJSON,
+		"yaml":    FormatYAML,
+	}, "{{.Context.Name}}\n")
+}
+
+func FormatContextCurrent(data bool) (string, error) {
+	if data {
+		return "*", nil
+	}
+	return "", nil
+}
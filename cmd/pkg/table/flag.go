@@ -2,6 +2,25 @@ package table
 
 import "github.com/urfave/cli"
 
+var outputTableFlags = []cli.Flag{
+	cli.StringFlag{
+		Name:  "columns",
+		Usage: "Comma separated columns to display, e.g. 'address,role'",
+	},
+	cli.StringFlag{
+		Name:  "sort-by",
+		Usage: "Sort by a column as displayed, numerically for numbers",
+	},
+	cli.BoolFlag{
+		Name:  "no-headers",
+		Usage: "Do not print the column headers",
+	},
+	cli.StringSliceFlag{
+		Name:  "filter",
+		Usage: "Only display the rows where the column has, column=value, or has not, column!=value, the value, may be repeated",
+	},
+}
+
 var outputServerFlags = []cli.Flag{
 	cli.BoolFlag{
 		Name:  "quiet,q",
@@ -9,7 +28,7 @@ var outputServerFlags = []cli.Flag{
 	},
 	cli.StringFlag{
 		Name:  "format",
-		Usage: "'json' or 'yaml'",
+		Usage: "'json', 'yaml', the 'wide' or 'narrow' column preset, or a go template",
 	},
 	cli.BoolFlag{
 		Name:  "ids",
@@ -20,7 +39,7 @@ var outputServerFlags = []cli.Flag{
 var outputNodeFlags = []cli.Flag{
 	cli.StringFlag{
 		Name:  "format",
-		Usage: "'json' or 'yaml'",
+		Usage: "'json', 'yaml', the 'wide' or 'narrow' column preset, or a go template",
 	},
 	cli.BoolFlag{
 		Name:  "ids",
@@ -28,10 +47,44 @@ var outputNodeFlags = []cli.Flag{
 	},
 }
 
+var outputContextFlags = []cli.Flag{
+	cli.BoolFlag{
+		Name:  "quiet,q",
+		Usage: "Only display names",
+	},
+	cli.StringFlag{
+		Name:  "format",
+		Usage: "'json', 'yaml', the 'wide' or 'narrow' column preset, or a go template",
+	},
+}
+
+var outputConfigFlags = []cli.Flag{
+	cli.BoolFlag{
+		Name:  "quiet,q",
+		Usage: "Only display revisions",
+	},
+	cli.StringFlag{
+		Name:  "format",
+		Usage: "'json', 'yaml', the 'wide' or 'narrow' column preset, or a go template",
+	},
+}
+
 func WriterServerFlags() []cli.Flag {
-	return outputServerFlags
+	return withTableFlags(outputServerFlags)
 }
 
 func WriterNodeFlags() []cli.Flag {
-	return outputNodeFlags
+	return withTableFlags(outputNodeFlags)
+}
+
+func WriterContextFlags() []cli.Flag {
+	return withTableFlags(outputContextFlags)
+}
+
+func WriterConfigFlags() []cli.Flag {
+	return withTableFlags(outputConfigFlags)
+}
+
+func withTableFlags(flags []cli.Flag) []cli.Flag {
+	return append(append([]cli.Flag{}, flags...), outputTableFlags...)
 }
@@ -1,33 +1,68 @@
 package table
 
 import (
-	"os"
-	"text/tabwriter"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/docker/go-units"
 	"github.com/urfave/cli"
+	"k8s.io/apimachinery/pkg/api/resource"
 )
 
 func NewNodeWriter(values [][]string, ctx *cli.Context) *Writer {
-	t := &Writer{
-		Writer: tabwriter.NewWriter(os.Stdout, 10, 1, 3, ' ', tabwriter.TabIndent),
-		funcMap: map[string]interface{}{
-			"json": FormatJSON,
-			"yaml": FormatYAML,
-		},
+	return newWriter(values, ctx, map[string]interface{}{
+		"age":      FormatNodeAge,
+		"seconds":  FormatNodeAgeSeconds,
+		"quantity": FormatNodeQuantity,
+		"join":     FormatNodeTaints,
+		"labels":   FormatNodeLabels,
+		"json":     FormatJSON,
+		"yaml":     FormatYAML,
+	}, "")
+}
+
+func FormatNodeAge(data *time.Time) (string, error) {
+	if data == nil || data.IsZero() {
+		return "", nil
 	}
-	t.HeaderFormat, t.ValueFormat = SimpleFormat(values)
-
-	customFormat := ctx.String("format")
-	if customFormat == "json" {
-		t.HeaderFormat = ""
-		t.ValueFormat = "json"
-	} else if customFormat == "yaml" {
-		t.HeaderFormat = ""
-		t.ValueFormat = "yaml"
-	} else if customFormat != "" {
-		t.ValueFormat = customFormat + "\n"
-		t.HeaderFormat = ""
+
+	return units.HumanDuration(time.Now().UTC().Sub(*data)), nil
+}
+
+// FormatNodeAgeSeconds returns the age in seconds, the AGE column is sorted on it.
+func FormatNodeAgeSeconds(data *time.Time) (string, error) {
+	if data == nil || data.IsZero() {
+		return "", nil
 	}
 
-	return t
+	return strconv.FormatInt(int64(time.Now().UTC().Sub(*data)/time.Second), 10), nil
+}
+
+// FormatNodeQuantity returns the milli value of the resource quantity, the
+// CPU and MEMORY columns are sorted on it.
+func FormatNodeQuantity(data string) (string, error) {
+	if data == "" {
+		return "", nil
+	}
+
+	quantity, err := resource.ParseQuantity(data)
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatInt(quantity.MilliValue(), 10), nil
+}
+
+func FormatNodeTaints(data []string) (string, error) {
+	return strings.Join(data, ","), nil
+}
+
+func FormatNodeLabels(data map[string]string) (string, error) {
+	labels := []string{}
+	for key, value := range data {
+		labels = append(labels, key+"="+value)
+	}
+	sort.Strings(labels)
+	return strings.Join(labels, ","), nil
 }
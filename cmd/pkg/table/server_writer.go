@@ -1,10 +1,9 @@
 package table
 
 import (
-	"fmt"
-	"os"
+	"net"
 	"strconv"
-	"text/tabwriter"
+	"strings"
 	"time"
 
 	"github.com/docker/docker/api/types"
@@ -23,38 +22,17 @@ func NewServerWriter(values [][]string, ctx *cli.Context) *Writer {
 		values = append(idsHeader, values...)
 	}
 
-	t := &Writer{
-		Writer: tabwriter.NewWriter(os.Stdout, 10, 1, 3, ' ', tabwriter.TabIndent),
-		funcMap: map[string]interface{}{
-			"id":   FormatContainerID,
-			"cmd":  FormatContainerCommand,
-			"port": FormatContainerPort,
-			"ago":  FormatContainerCreated,
-			"name": FormatContainerName,
-			"json": FormatJSON,
-			"yaml": FormatYAML,
-		},
-	}
-	t.HeaderFormat, t.ValueFormat = SimpleFormat(values)
-
-	if ctx.Bool("quiet") {
-		t.HeaderFormat = ""
-		t.ValueFormat = "{{.ID}}\n"
-	}
-
-	customFormat := ctx.String("format")
-	if customFormat == "json" {
-		t.HeaderFormat = ""
-		t.ValueFormat = "json"
-	} else if customFormat == "yaml" {
-		t.HeaderFormat = ""
-		t.ValueFormat = "yaml"
-	} else if customFormat != "" {
-		t.ValueFormat = customFormat + "\n"
-		t.HeaderFormat = ""
-	}
-
-	return t
+	return newWriter(values, ctx, map[string]interface{}{
+		"id":      FormatContainerID,
+		"cmd":     FormatContainerCommand,
+		"port":    FormatContainerPort,
+		"ago":     FormatContainerCreated,
+		"name":    FormatContainerName,
+		"latency": FormatServerLatency,
+		"mounts":  FormatContainerMounts,
+		"json":    FormatJSON,
+		"yaml":    FormatYAML,
+	}, "{{.ID}}\n")
 }
 
 func FormatContainerID(data interface{}) (string, error) {
@@ -97,16 +75,18 @@ func FormatContainerName(data []string) (string, error) {
 }
 
 func FormatContainerPort(data []types.Port) (string, error) {
-	ports := ""
-	for index, port := range data {
-		if index == 0 {
-			ports += port.IP + ":" + strconv.Itoa(int(port.PublicPort)) + "->" + strconv.Itoa(int(port.PrivatePort)) + "/" + port.Type
-		} else {
-			ports += " " + port.IP + ":" + strconv.Itoa(int(port.PublicPort)) + "->" + strconv.Itoa(int(port.PrivatePort)) + "/" + port.Type
+	ports := []string{}
+	for _, port := range data {
+		private := strconv.Itoa(int(port.PrivatePort)) + "/" + port.Type
+		if port.PublicPort == 0 {
+			// exposed but not published on the host
+			ports = append(ports, private)
+			continue
 		}
+		ports = append(ports, net.JoinHostPort(port.IP, strconv.Itoa(int(port.PublicPort)))+"->"+private)
 	}
 
-	return fmt.Sprintf("%s", ports), nil
+	return strings.Join(ports, " "), nil
 }
 
 func FormatContainerCreated(data interface{}) (string, error) {
@@ -122,3 +102,23 @@ func FormatContainerCreated(data interface{}) (string, error) {
 
 	return units.HumanDuration(time.Now().UTC().Sub(t)) + " ago", nil
 }
+
+func FormatContainerMounts(data []types.MountPoint) (string, error) {
+	mounts := []string{}
+	for _, m := range data {
+		mount := m.Source + ":" + m.Destination
+		if !m.RW {
+			mount += ":ro"
+		}
+		mounts = append(mounts, mount)
+	}
+
+	return strings.Join(mounts, ","), nil
+}
+
+func FormatServerLatency(latency time.Duration) (string, error) {
+	if latency <= 0 {
+		return "", nil
+	}
+	return latency.Round(time.Millisecond).String(), nil
+}
@@ -12,4 +12,9 @@ type Writer struct {
 	headerPrinted bool
 	Writer        *tabwriter.Writer
 	funcMap       map[string]interface{}
+	collect       bool
+	columns       []column
+	filters       []filter
+	sortBy        int
+	rows          []row
 }
@@ -1,15 +1,59 @@
 package table
 
 import (
+	"bytes"
 	"encoding/json"
 	"io"
+	"os"
+	"text/tabwriter"
 	"text/template"
 
+	"github.com/urfave/cli"
 	"gopkg.in/yaml.v2"
 )
 
 type FormatFunc interface{}
 
+// newWriter returns a writer printing the {header, template[, preset[, sort
+// key]]} columns to stdout, set up by the output flags of the command.
+func newWriter(values [][]string, ctx *cli.Context, funcMap map[string]interface{}, quietFormat string) *Writer {
+	t := &Writer{
+		Writer:  tabwriter.NewWriter(os.Stdout, 10, 1, 3, ' ', tabwriter.TabIndent),
+		funcMap: funcMap,
+		columns: parseColumns(values),
+		sortBy:  -1,
+	}
+
+	customFormat := ctx.String("format")
+	preset := ""
+	if customFormat == PresetWide || customFormat == PresetNarrow {
+		preset, customFormat = customFormat, ""
+	}
+	t.configure(ctx, preset)
+
+	if ctx.Bool("quiet") && quietFormat != "" {
+		t.HeaderFormat = ""
+		t.ValueFormat = quietFormat
+	}
+
+	if customFormat == "json" {
+		t.HeaderFormat = ""
+		t.ValueFormat = "json"
+	} else if customFormat == "yaml" {
+		t.HeaderFormat = ""
+		t.ValueFormat = "yaml"
+	} else if customFormat != "" {
+		t.ValueFormat = customFormat + "\n"
+		t.HeaderFormat = ""
+	}
+
+	if ctx.Bool("no-headers") {
+		t.HeaderFormat = ""
+	}
+
+	return t
+}
+
 func (w *Writer) AddFormatFunc(name string, f FormatFunc) {
 	w.funcMap[name] = f
 }
@@ -18,6 +62,23 @@ func (w *Writer) Err() error {
 	return w.err
 }
 
+// Collect keeps the written objects instead of printing them.
+func (w *Writer) Collect() {
+	w.collect = true
+}
+
+// Items returns the objects written while collecting, filtered and sorted.
+func (w *Writer) Items() []interface{} {
+	if w.sortBy >= 0 {
+		sortRows(w.rows)
+	}
+	items := []interface{}{}
+	for _, r := range w.rows {
+		items = append(items, r.obj)
+	}
+	return items
+}
+
 func (w *Writer) writeHeader() {
 	if w.HeaderFormat != "" && !w.headerPrinted {
 		w.headerPrinted = true
@@ -28,40 +89,91 @@ func (w *Writer) writeHeader() {
 	}
 }
 
+// Write prints the object unless it is filtered out, the objects are kept
+// until Close when they are sorted.
 func (w *Writer) Write(obj interface{}) {
 	if w.err != nil {
 		return
 	}
 
-	w.writeHeader()
-	if w.err != nil {
+	matched, err := w.matches(obj)
+	if err != nil || !matched {
+		w.err = err
 		return
 	}
 
+	key := ""
+	if w.sortBy >= 0 {
+		if key, w.err = w.sortKey(obj); w.err != nil {
+			return
+		}
+	}
+
+	if w.collect {
+		w.rows = append(w.rows, row{obj: obj, key: key})
+		return
+	}
+
+	// render right away so that a template error is reported by Err
+	text, err := w.render(obj)
+	if err != nil {
+		w.err = err
+		return
+	}
+	if w.sortBy >= 0 {
+		w.rows = append(w.rows, row{obj: obj, text: text, key: key})
+		return
+	}
+	w.writeText(text)
+}
+
+func (w *Writer) render(obj interface{}) (string, error) {
 	if w.ValueFormat == "json" {
 		content, err := json.Marshal(obj)
-		w.err = err
-		if w.err != nil {
-			return
+		if err != nil {
+			return "", err
 		}
-		_, w.err = w.Writer.Write(append(content, byte('\n')))
+		return string(content) + "\n", nil
 	} else if w.ValueFormat == "yaml" {
 		content, err := yaml.Marshal(obj)
-		w.err = err
-		if w.err != nil {
-			return
+		if err != nil {
+			return "", err
 		}
-		w.Writer.Write([]byte("---\n"))
-		_, w.err = w.Writer.Write(append(content, byte('\n')))
-	} else {
-		w.err = w.printTemplate(w.Writer, w.ValueFormat, obj)
+		return "---\n" + string(content) + "\n", nil
+	}
+
+	buf := bytes.Buffer{}
+	if err := w.printTemplate(&buf, w.ValueFormat, obj); err != nil {
+		return "", err
 	}
+	return buf.String(), nil
+}
+
+func (w *Writer) writeText(text string) {
+	w.writeHeader()
+	if w.err != nil {
+		return
+	}
+	_, w.err = w.Writer.Write([]byte(text))
 }
 
 func (w *Writer) Close() error {
 	if w.err != nil {
 		return w.err
 	}
+	if w.collect {
+		return nil
+	}
+	if w.sortBy >= 0 {
+		sortRows(w.rows)
+		for _, r := range w.rows {
+			w.writeText(r.text)
+			if w.err != nil {
+				return w.err
+			}
+		}
+		w.rows = nil
+	}
 	w.writeHeader()
 	if w.err != nil {
 		return w.err
@@ -2,6 +2,9 @@ package cmd
 
 import (
 	"fmt"
+	"os"
+
+	"github.com/cnrancher/cube-cli/util"
 
 	"github.com/c-bata/go-prompt"
 	"github.com/urfave/cli"
@@ -18,6 +21,14 @@ func PromptCommand() cli.Command {
 }
 
 func promptAction(ctx *cli.Context) error {
+	// keep the context the prompt was started with
+	if name := ctx.GlobalString(ContextFlag); name != "" {
+		os.Setenv(util.ContextEnv, name)
+	}
+	if host := ctx.GlobalString(DockerHostFlag); host != "" {
+		os.Setenv(util.DockerHostEnv, host)
+	}
+
 	fmt.Print("cube cli auto-completion mode")
 	defer fmt.Println("Goodbye!")
 	p := prompt.New(
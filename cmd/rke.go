@@ -1,28 +1,99 @@
 package cmd
 
 import (
+	"fmt"
 	"os"
 
+	"github.com/rancher/rke/cluster"
 	rkecmd "github.com/rancher/rke/cmd"
+	"github.com/rancher/rke/pki"
 	"github.com/urfave/cli"
 )
 
+// rkeChanges describes the change made by the rke commands which succeeded.
+var rkeChanges = map[string]func(ctx *cli.Context) string{
+	"up": func(ctx *cli.Context) string {
+		return fmt.Sprintf("kubernetes cluster of %s is up", ctx.String("config"))
+	},
+	"remove": func(ctx *cli.Context) string {
+		return fmt.Sprintf("kubernetes cluster of %s removed", ctx.String("config"))
+	},
+	"snapshot-save": func(ctx *cli.Context) string {
+		return fmt.Sprintf("etcd snapshot %s saved", ctx.String("name"))
+	},
+	"snapshot-restore": func(ctx *cli.Context) string {
+		return fmt.Sprintf("etcd snapshot %s restored", ctx.String("name"))
+	},
+}
+
 func RKECommand() cli.Command {
+	versionCommand := rkecmd.VersionCommand()
+	versionCommand.Action = rkeVersion
+
 	return cli.Command{
 		Name:        "rke",
 		Usage:       "Mapping the RKE commands",
 		Description: "Manage the RancherCUBE Kubernetes",
 		Before: func(c *cli.Context) error {
+			startAction(c)
 			if os.Getenv("RKE_CONFIG") == "" {
-				os.Setenv("RKE_CONFIG", RKEConfigDefault)
+				cubeCtx, err := currentContext(c)
+				if err != nil {
+					return err
+				}
+				os.Setenv("RKE_CONFIG", cubeCtx.RKEConfig)
 			}
 			return nil
 		},
 		Subcommands: []cli.Command{
-			rkecmd.UpCommand(),
-			rkecmd.RemoveCommand(),
-			rkecmd.VersionCommand(),
-			rkecmd.EtcdCommand(),
+			rkeAction(rkecmd.UpCommand()),
+			rkeAction(rkecmd.RemoveCommand()),
+			rkeAction(versionCommand),
+			rkeAction(rkecmd.EtcdCommand()),
 		},
 	}
 }
+
+// rkeAction runs the rke command actions like the cube ones, so that they
+// report their result too.
+func rkeAction(command cli.Command) cli.Command {
+	if action, ok := command.Action.(func(*cli.Context) error); ok {
+		name := command.Name
+		command.Action = defaultAction(func(ctx *cli.Context) error {
+			addTarget(ctx.String("config"))
+			if name == "remove" && structuredOutput() && !ctx.Bool("force") {
+				return usageErrorf("cube rke remove: --force is required with --%s, there is no confirmation prompt", OutputFlag)
+			}
+
+			if err := action(ctx); err != nil {
+				return err
+			}
+			if change, ok := rkeChanges[name]; ok {
+				addChange("%s", change(ctx))
+			}
+			return nil
+		})
+	}
+
+	for i := range command.Subcommands {
+		command.Subcommands[i] = rkeAction(command.Subcommands[i])
+	}
+	return command
+}
+
+// rkeVersion prints the kubernetes version of the cluster as rke does, or
+// records it in the result.
+func rkeVersion(ctx *cli.Context) error {
+	localKubeConfig := pki.GetLocalKubeConfig(ctx.String("config"), "")
+	serverVersion, err := cluster.GetK8sVersion(localKubeConfig, nil)
+	if err != nil {
+		return unavailableErrorf("%v", err)
+	}
+
+	if structuredOutput() {
+		setData(serverVersion)
+		return nil
+	}
+	fmt.Printf("Server Version: %s\n", serverVersion)
+	return nil
+}
@@ -1,17 +1,31 @@
 package cmd
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"io/ioutil"
+	"net"
+	"net/url"
 	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/cnrancher/cube-cli/cmd/pkg/table"
 	"github.com/cnrancher/cube-cli/docker"
+	"github.com/cnrancher/cube-cli/k8s"
+	"github.com/cnrancher/cube-cli/util"
 
+	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/mount"
 	"github.com/docker/docker/api/types/strslice"
+	"github.com/docker/docker/client"
 	"github.com/docker/go-connections/nat"
+	"github.com/sirupsen/logrus"
 	"github.com/urfave/cli"
 )
 
@@ -22,18 +36,87 @@ Management RancherCUBE API-SERVER.
 Example:
 	# Run the RancherCUBE api-server
 	$ cube server run --port "9600"
+	# Run the RancherCUBE api-server on a single host address
+	$ cube server run --bind-address 127.0.0.1 --port 9700
+	# Run the RancherCUBE api-server behind a proxy with debug logs, the settings are kept for later runs
+	$ cube server run --env HTTPS_PROXY=http://proxy:3128 --volume /etc/cube/certs:/certs:ro --server-arg --debug
+	# Run the RancherCUBE api-server with a kubernetes config imported into the data directory
+	$ cube server run --kube-config ~/.kube/config
+	# Run the RancherCUBE api-server with a kubernetes config mounted from where it is
+	$ cube server run --kube-config /etc/cube/kube_config.yml --mount-kube-config
+	# Clear the kept environment variables
+	$ cube server run --env=
+	# Recreate the RancherCUBE api-server even if its spec is unchanged
+	$ cube server run --force-recreate
+	# Run a specific RancherCUBE api-server build mirrored to a private registry
+	$ cube server run --registry registry.example.com:5000 --tag v0.1.0
+	# Run the RancherCUBE api-server pulled from an authenticated registry
+	$ cube server run --registry harbor.example.com/library --registry-user admin --registry-password <password>
+	# Upgrade the RancherCUBE api-server, it is rolled back if the new one is not healthy in time
+	$ cube server upgrade --tag v0.2.0 --timeout 5m
 	# Stop the RancherCUBE api-server
 	$ cube server stop
 	# Remove the RancherCUBE api-server
 	$ cube server rm
 	# Get the RancherCUBE api-server status
 	$ cube server status
+	# Follow the RancherCUBE api-server logs of the last 10 minutes
+	$ cube server logs --follow --since 10m
+	# Get the RancherCUBE api-server status from a remote docker host, DOCKER_TLS, DOCKER_TLS_VERIFY and DOCKER_CERT_PATH are honored
+	$ cube --docker-host tcp://10.0.0.1:2376 server status
+	# Wait for the RancherCUBE api-server to become healthy
+	$ cube server status --wait --timeout 5m
+	# Run the RancherCUBE api-server and print the result as json, the exit code tells the failure kind
+	$ cube --output json server run
 `
 
-	ServerPort     = "port"
-	ConfigLocation = "kube-config"
+	ServerPort       = "port"
+	BindAddress      = "bind-address"
+	ServerImage      = "image"
+	ServerTag        = "tag"
+	ServerRegistry   = "registry"
+	RegistryUser     = "registry-user"
+	RegistryPassword = "registry-password"
+	ConfigLocation   = "kube-config"
+	ForceRecreate    = "force-recreate"
+	MountKubeConfig  = "mount-kube-config"
+	ServerWait       = "wait"
+	ServerEnv        = "env"
+	ServerVolume     = "volume"
+	ServerArg        = "server-arg"
+	LogsFollow       = "follow"
+	LogsTail         = "tail"
+	LogsSince        = "since"
+	LogsTimestamps   = "timestamps"
 )
 
+const (
+	ServerHealthy     = "healthy"
+	ServerUnhealthy   = "unhealthy"
+	ServerUnreachable = "unreachable"
+
+	serverProbeTimeout     = 5 * time.Second
+	serverWaitPollInterval = 2 * time.Second
+)
+
+// ServerOutput is the api-server container along with the health probed on
+// its published port.
+type ServerOutput struct {
+	types.Container `yaml:",inline"`
+	Health          string        `yaml:"health" json:"health"`
+	Reachable       bool          `yaml:"reachable" json:"reachable"`
+	Latency         time.Duration `yaml:"latency" json:"latency"`
+	Version         string        `yaml:"version" json:"version"`
+	RestartCount    int           `yaml:"restartCount" json:"restartCount"`
+	ExitCode        int           `yaml:"exitCode" json:"exitCode"`
+}
+
+// LogsOutput is the api-server logs printed within the command result.
+type LogsOutput struct {
+	Stdout string `yaml:"stdout" json:"stdout"`
+	Stderr string `yaml:"stderr" json:"stderr"`
+}
+
 func ServerCommand() cli.Command {
 	return cli.Command{
 		Name:        "server",
@@ -41,26 +124,106 @@ func ServerCommand() cli.Command {
 		Usage:       "Operations with cube api-server",
 		Description: ServerDescription,
 		Action:      defaultAction(serverStatus),
-		Flags:       table.WriterServerFlags(),
+		Flags:       serverStatusFlags(),
 		Subcommands: []cli.Command{
 			{
 				Name:        "run",
 				Usage:       "Run the RancherCUBE api-server",
-				Description: "Run the RancherCUBE api-server",
+				Description: "Run the RancherCUBE api-server, an existing api-server container is recreated when its spec changes",
 				Flags: []cli.Flag{
 					cli.StringFlag{
 						Name:  ServerPort,
 						Value: APIServerPortDefault,
 						Usage: "Specify api-server listen port",
 					},
+					cli.StringFlag{
+						Name:  BindAddress,
+						Value: APIServerBindAddressDefault,
+						Usage: "Specify the host address api-server listen port is bound on",
+					},
+					cli.StringFlag{
+						Name:  ServerImage,
+						Value: APIServerImage,
+						Usage: "Specify api-server image repository",
+					},
+					cli.StringFlag{
+						Name:  ServerTag,
+						Usage: "Specify api-server image tag, default to the cube version",
+					},
+					cli.StringFlag{
+						Name:  ServerRegistry,
+						Usage: "Specify the registry hosting the api-server image, e.g. a mirror for air-gapped sites",
+					},
+					cli.StringFlag{
+						Name:  RegistryUser,
+						Usage: "Specify the user of the api-server image registry, default to the rke private registries or docker login",
+					},
+					cli.StringFlag{
+						Name:   RegistryPassword,
+						Usage:  "Specify the password of the api-server image registry",
+						EnvVar: "CUBE_REGISTRY_PASSWORD",
+					},
 					cli.StringFlag{
 						Name:  ConfigLocation,
-						Value: KubeConfigLocation,
-						Usage: "Specify api-server kubernetes config location",
+						Usage: "Specify api-server kubernetes config location, it is copied to <data-dir>/" + KubeConfigName + " after the previous one is backed up",
+					},
+					cli.BoolFlag{
+						Name:  MountKubeConfig,
+						Usage: "Mount the kubernetes config given by --" + ConfigLocation + " into the api-server instead of copying it",
+					},
+					cli.StringSliceFlag{
+						Name:  ServerEnv,
+						Usage: "Specify an api-server environment variable KEY=VALUE, or KEY to take the local value on every run, may be repeated, kept in the server config for later runs",
+					},
+					cli.StringSliceFlag{
+						Name:  ServerVolume,
+						Usage: "Specify an api-server volume host-path:container-path[:ro], may be repeated, kept in the server config for later runs",
+					},
+					cli.StringSliceFlag{
+						Name:  ServerArg,
+						Usage: "Specify an extra api-server argument, may be repeated, kept in the server config for later runs",
+					},
+					cli.BoolFlag{
+						Name:  ForceRecreate,
+						Usage: "Recreate the api-server container even if its spec is unchanged",
 					},
 				},
 				Action: defaultAction(serverRun),
 			},
+			{
+				Name:        "upgrade",
+				Usage:       "Upgrade the RancherCUBE api-server image",
+				Description: "Upgrade the RancherCUBE api-server image, the previous container is restored if the new one is not healthy in time",
+				Flags: []cli.Flag{
+					cli.StringFlag{
+						Name:  ServerImage,
+						Usage: "Specify api-server image repository, default to the repository of the running api-server",
+					},
+					cli.StringFlag{
+						Name:  ServerTag,
+						Usage: "Specify api-server image tag, default to the cube version",
+					},
+					cli.StringFlag{
+						Name:  ServerRegistry,
+						Usage: "Specify the registry hosting the api-server image, e.g. a mirror for air-gapped sites",
+					},
+					cli.StringFlag{
+						Name:  RegistryUser,
+						Usage: "Specify the user of the api-server image registry, default to the rke private registries or docker login",
+					},
+					cli.StringFlag{
+						Name:   RegistryPassword,
+						Usage:  "Specify the password of the api-server image registry",
+						EnvVar: "CUBE_REGISTRY_PASSWORD",
+					},
+					cli.DurationFlag{
+						Name:  Timeout,
+						Value: docker.ContainerHealthTimeoutDefault,
+						Usage: "Specify how long to wait for the new api-server to become healthy",
+					},
+				},
+				Action: defaultAction(serverUpgrade),
+			},
 			{
 				Name:        "stop",
 				Usage:       "Stop the RancherCUBE api-server",
@@ -73,129 +236,707 @@ func ServerCommand() cli.Command {
 				Description: "Remove the RancherCUBE api-server",
 				Action:      defaultAction(serverRm),
 			},
+			{
+				Name:        "logs",
+				Usage:       "Show the RancherCUBE api-server logs",
+				Description: "Show the RancherCUBE api-server logs, stdout and stderr are kept apart",
+				Flags: []cli.Flag{
+					cli.BoolFlag{
+						Name:  LogsFollow + ", f",
+						Usage: "Follow the log output",
+					},
+					cli.StringFlag{
+						Name:  LogsTail,
+						Value: "all",
+						Usage: "Specify the number of lines to show from the end of the logs",
+					},
+					cli.StringFlag{
+						Name:  LogsSince,
+						Usage: "Show the logs since a timestamp, e.g. 2018-06-01T10:00:00, or a relative duration, e.g. 10m",
+					},
+					cli.BoolFlag{
+						Name:  LogsTimestamps + ", t",
+						Usage: "Show the timestamps",
+					},
+				},
+				Action: defaultAction(serverLogs),
+			},
 			{
 				Name:        "status",
 				Usage:       "Status the RancherCUBE api-server",
 				Description: "Status the RancherCUBE api-server",
-				Flags:       table.WriterServerFlags(),
+				Flags:       serverStatusFlags(),
 				Action:      defaultAction(serverStatus),
 			},
 		},
 	}
 }
 
+func serverStatusFlags() []cli.Flag {
+	return append([]cli.Flag{
+		cli.BoolFlag{
+			Name:  ServerWait,
+			Usage: "Wait for the api-server to become healthy",
+		},
+		cli.DurationFlag{
+			Name:  Timeout,
+			Value: docker.ContainerHealthTimeoutDefault,
+			Usage: "Specify how long to wait for the api-server to become healthy",
+		},
+	}, table.WriterServerFlags()...)
+}
+
 func serverRun(ctx *cli.Context) error {
 	port := ctx.String(ServerPort)
-	configLocation := ctx.String(ConfigLocation)
-	if "" == configLocation {
-		return fmt.Errorf("cube server run: require %v", ConfigLocation)
+	if err := util.ValidatePort(port); err != nil {
+		return usageErrorf("cube server run: %v", err)
+	}
+	bindAddress := ctx.String(BindAddress)
+	if err := util.ValidateBindAddress(bindAddress); err != nil {
+		return usageErrorf("cube server run: %v", err)
 	}
 
-	if configLocation != KubeConfigLocation {
-		err := os.Rename(configLocation, KubeConfigLocation)
+	cubeCtx, err := currentContext(ctx)
+	if err != nil {
+		return err
+	}
+	addTarget(cubeCtx.ContainerName)
+
+	serverConfigFile := serverConfigLocation(cubeCtx)
+	serverConfig, serverConfigChanged, err := applyServerConfig(ctx, serverConfigFile)
+	if err != nil {
+		return fmt.Errorf("cube server run: %v", err)
+	}
+	env, err := util.ParseEnv(serverConfig.Env)
+	if err != nil {
+		return usageErrorf("cube server run: %v", err)
+	}
+
+	// the image flags win over the image kept by an upgrade, and replace it
+	image := serverConfig.Image
+	if image == "" || ctx.IsSet(ServerRegistry) || ctx.IsSet(ServerImage) || ctx.IsSet(ServerTag) {
+		image, err = docker.ImageName(ctx.String(ServerRegistry), ctx.String(ServerImage), ctx.String(ServerTag), APIServerTagDefault(ctx.App.Version))
 		if err != nil {
-			return err
+			return usageErrorf("cube server run: %v", err)
+		}
+		if serverConfig.Image != "" && serverConfig.Image != image {
+			serverConfig.Image = image
+			serverConfigChanged = true
 		}
 	}
 
+	kubeConfigLocation := cubeCtx.KubeConfig
+	configLocation := ctx.String(ConfigLocation)
+	mountedKubeConfig := ""
+	if configLocation != "" {
+		if ctx.Bool(MountKubeConfig) {
+			mountedKubeConfig, err = filepath.Abs(configLocation)
+			if err == nil {
+				err = validateKubeConfig(mountedKubeConfig)
+			}
+		} else {
+			err = importKubeConfig(configLocation, kubeConfigLocation)
+		}
+		if err != nil {
+			return prefixError("cube server run", err)
+		}
+	} else if ctx.Bool(MountKubeConfig) {
+		return usageErrorf("cube server run: %s requires %s", MountKubeConfig, ConfigLocation)
+	}
+
 	context := context.Background()
 
-	dClient, err := docker.NewClient(context, docker.SystemDockerSock)
+	dClient, err := docker.NewClient(context, cubeCtx.DockerHost)
 	if err != nil {
 		return err
 	}
 
-	// assemble *container.Config
-	exposedPort, err := nat.NewPort("tcp", "9600")
+	if err := checkServerPort(context, dClient, cubeCtx, bindAddress, port); err != nil {
+		return prefixError("cube server run", err)
+	}
+
+	// only the kubernetes config file is shared with the api-server
+	kubeConfigSource, err := filepath.Abs(kubeConfigLocation)
 	if err != nil {
 		return err
 	}
-	exports := make(nat.PortSet, 1)
-	exports[exposedPort] = struct{}{}
-	containerConfig := &container.Config{
-		Image: APIServerImage,
-		Cmd: strslice.StrSlice{
-			"serve",
-			"--listen-addr=0.0.0.0:9600",
+	if mountedKubeConfig != "" {
+		kubeConfigSource = mountedKubeConfig
+	}
+	if _, err := os.Stat(kubeConfigSource); err != nil {
+		return notFoundErrorf("cube server run: kubernetes config %s not found, specify it with --%s", kubeConfigSource, ConfigLocation)
+	}
+	mounts := []mount.Mount{
+		{
+			Type:     mount.TypeBind,
+			Source:   kubeConfigSource,
+			Target:   path.Join(APIServerKubeConfig, KubeConfigName),
+			ReadOnly: true,
 		},
-		ExposedPorts: exports,
+	}
+	for _, v := range serverConfig.Volumes {
+		volume, err := util.ParseVolume(v)
+		if err != nil {
+			return usageErrorf("cube server run: %v", err)
+		}
+		mounts = append(mounts, mount.Mount{
+			Type:     mount.TypeBind,
+			Source:   volume.Source,
+			Target:   volume.Target,
+			ReadOnly: volume.ReadOnly,
+		})
+	}
+
+	// keep the settings once they are known to be valid, a bare KEY is kept
+	// as it is so that its local value is not written to the disk
+	if serverConfigChanged {
+		if err := util.WriteServerConfig(serverConfig, serverConfigFile); err != nil {
+			return fmt.Errorf("cube server run: %v", err)
+		}
+		addChange("server config saved to %s", serverConfigFile)
+	}
+
+	// assemble *container.Config
+	containerConfig, err := apiServerContainerConfig(image, port, env, serverConfig)
+	if err != nil {
+		return err
 	}
 
 	// assemble *container.HostConfig
+	exposedPort := nat.Port(port + "/tcp")
 	hostConfig := &container.HostConfig{
 		RestartPolicy: container.RestartPolicy{
 			Name:              "always",
 			MaximumRetryCount: 0,
 		},
-		Mounts: []mount.Mount{
-			{
-				Type:   mount.TypeBind,
-				Source: APIServerKubeConfig,
-				Target: APIServerKubeConfig,
-			},
-		},
+		Mounts: mounts,
 		PortBindings: nat.PortMap{
-			"9600/tcp": []nat.PortBinding{
+			exposedPort: []nat.PortBinding{
 				{
-					HostIP:   "0.0.0.0",
-					HostPort: port + "/tcp",
+					HostIP:   bindAddress,
+					HostPort: port,
 				},
 			},
 		},
 	}
 
-	return docker.CreateOrRestart(context, dClient, containerConfig, hostConfig, nil, APIServerContainerName)
+	prsMap, err := registryCredentials(ctx, cubeCtx, image)
+	if err != nil {
+		return fmt.Errorf("cube server run: %v", err)
+	}
+
+	action, err := docker.CreateOrRestart(context, dClient, containerConfig, hostConfig, nil, cubeCtx.ContainerName, prsMap, ctx.Bool(ForceRecreate))
+	if err != nil {
+		return err
+	}
+	addChange("container %s %s", cubeCtx.ContainerName, action)
+	return nil
+}
+
+func validateKubeConfig(kubeConfig string) error {
+	version, err := k8s.ValidateKubeConfig(kubeConfig, TimeoutDefault)
+	if err != nil {
+		return err
+	}
+	logrus.Infof("cube server run: kubernetes config %s reaches kubernetes %s", kubeConfig, version)
+	return nil
+}
+
+// importKubeConfig copies the kubernetes config to the location the api-server
+// reads, the source is left in place and the previous copy is backed up.
+func importKubeConfig(source, target string) error {
+	sourcePath, err := filepath.Abs(source)
+	if err != nil {
+		return err
+	}
+	targetPath, err := filepath.Abs(target)
+	if err != nil {
+		return err
+	}
+	if err := validateKubeConfig(sourcePath); err != nil {
+		return err
+	}
+	if sourcePath == targetPath {
+		return nil
+	}
+
+	data, err := ioutil.ReadFile(sourcePath)
+	if err != nil {
+		return err
+	}
+	if previous, err := ioutil.ReadFile(targetPath); err == nil && bytes.Equal(previous, data) {
+		return nil
+	}
+
+	backup, err := util.BackupFile(targetPath, util.KubeConfigBackupLimit)
+	if err != nil {
+		return fmt.Errorf("backup kubernetes config %s: %v", targetPath, err)
+	}
+	if backup != "" {
+		logrus.Infof("cube server run: previous kubernetes config backed up to %s", backup)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(targetPath), 0755); err != nil {
+		return err
+	}
+	// the kubernetes config holds the cluster credentials
+	if err := util.WriteFileAtomic(targetPath, data, 0600); err != nil {
+		return err
+	}
+	addChange("kubernetes config %s copied to %s", sourcePath, targetPath)
+	return nil
+}
+
+// serverConfigLocation returns the server config kept beside the rke config of the context.
+func serverConfigLocation(cubeCtx *util.Context) string {
+	return filepath.Join(filepath.Dir(cubeCtx.RKEConfig), util.ServerConfigName)
+}
+
+// applyServerConfig merges the flags into the kept server config, a flag
+// replaces the kept values and an empty flag value clears them. It reports
+// whether the config has to be saved.
+func applyServerConfig(ctx *cli.Context, filename string) (*util.ServerConfig, bool, error) {
+	config, err := util.ReadServerConfig(filename)
+	if err != nil {
+		return nil, false, err
+	}
+
+	changed := false
+	for flag, values := range map[string]*[]string{
+		ServerEnv:    &config.Env,
+		ServerVolume: &config.Volumes,
+		ServerArg:    &config.ServerArgs,
+	} {
+		if !ctx.IsSet(flag) {
+			continue
+		}
+		*values = []string{}
+		for _, value := range ctx.StringSlice(flag) {
+			if value != "" {
+				*values = append(*values, value)
+			}
+		}
+		changed = true
+	}
+
+	return config, changed, nil
+}
+
+// apiServerContainerConfig assembles the api-server container config, the
+// api-server listens on the port inside the container too.
+func apiServerContainerConfig(image, port string, env []string, serverConfig *util.ServerConfig) (*container.Config, error) {
+	exposedPort, err := nat.NewPort("tcp", port)
+	if err != nil {
+		return nil, err
+	}
+	exports := make(nat.PortSet, 1)
+	exports[exposedPort] = struct{}{}
+
+	return &container.Config{
+		Image: image,
+		Cmd: append(strslice.StrSlice{
+			"serve",
+			"--listen-addr=" + net.JoinHostPort(APIServerBindAddressDefault, port),
+		}, serverConfig.ServerArgs...),
+		Env:          env,
+		ExposedPorts: exports,
+	}, nil
+}
+
+// checkServerPort fails early when the host port is taken by another process,
+// docker would only report it once the container is created. The port can
+// only be probed when docker runs on this host, and is not probed when the
+// running api-server already holds the same binding, it releases it on restart.
+func checkServerPort(ctx context.Context, dClient *client.Client, cubeCtx *util.Context, bindAddress, port string) error {
+	if !strings.HasPrefix(cubeCtx.DockerHost, "unix://") {
+		logrus.Debugf("cube server run: skip port check on remote docker host %s", cubeCtx.DockerHost)
+		return nil
+	}
+
+	container, err := docker.StatusContainer(ctx, dClient, cubeCtx.ContainerName)
+	if err != nil {
+		return err
+	}
+	if container.State == "running" && hasPortBinding(container.Ports, bindAddress, port) {
+		return nil
+	}
+
+	if err := util.CheckPortAvailable(bindAddress, port); err != nil {
+		return &CommandError{code: ExitConflict, err: err}
+	}
+	return nil
+}
+
+// hasPortBinding tells whether one of the container ports publishes the port
+// on the bind address.
+func hasPortBinding(ports []types.Port, bindAddress, port string) bool {
+	for _, p := range ports {
+		ip := p.IP
+		if ip == "" {
+			ip = APIServerBindAddressDefault
+		}
+		if ip == bindAddress && strconv.Itoa(int(p.PublicPort)) == port {
+			return true
+		}
+	}
+	return false
+}
+
+// registryCredentials collects the registry credentials from docker login,
+// the rke private registries and the flags, the later ones win.
+func registryCredentials(ctx *cli.Context, cubeCtx *util.Context, image string) (map[string]docker.PrivateRegistry, error) {
+	prsMap, err := docker.ReadDockerConfigAuths(docker.DockerConfigFile())
+	if err != nil {
+		logrus.Warnf("cube server run: skip docker login credentials: %v", err)
+		prsMap = map[string]docker.PrivateRegistry{}
+	}
+
+	config, err := readRKEConfig(cubeCtx.RKEConfig)
+	if err != nil {
+		logrus.Debugf("cube server run: skip rke private registries: %v", err)
+	} else {
+		for _, pr := range config.PrivateRegistries {
+			if pr.User == "" {
+				continue
+			}
+			domain := docker.RegistryDomain(pr.URL)
+			prsMap[domain] = docker.PrivateRegistry{
+				URL:      domain,
+				User:     pr.User,
+				Password: pr.Password,
+			}
+		}
+	}
+
+	if ctx.String(RegistryUser) != "" {
+		domain, err := docker.ImageDomain(image)
+		if err != nil {
+			return nil, err
+		}
+		prsMap[domain] = docker.PrivateRegistry{
+			URL:      domain,
+			User:     ctx.String(RegistryUser),
+			Password: ctx.String(RegistryPassword),
+		}
+	} else if ctx.String(RegistryPassword) != "" {
+		logrus.Warnf("cube server run: %s is ignored without %s", RegistryPassword, RegistryUser)
+	}
+
+	return prsMap, nil
+}
+
+func serverUpgrade(ctx *cli.Context) error {
+	cubeCtx, err := currentContext(ctx)
+	if err != nil {
+		return err
+	}
+	addTarget(cubeCtx.ContainerName)
+
+	context := context.Background()
+
+	dClient, err := docker.NewClient(context, cubeCtx.DockerHost)
+	if err != nil {
+		return err
+	}
+
+	current, err := docker.StatusContainer(context, dClient, cubeCtx.ContainerName)
+	if err != nil {
+		return err
+	}
+	if current.ID == "" {
+		return notFoundErrorf("cube server upgrade: api-server container %s not found, use cube server run", cubeCtx.ContainerName)
+	}
+
+	repository := ctx.String(ServerImage)
+	if repository == "" {
+		repository, err = docker.ImageRepository(current.Image)
+		if err != nil {
+			return fmt.Errorf("cube server upgrade: %v", err)
+		}
+	}
+	image, err := docker.ImageName(ctx.String(ServerRegistry), repository, ctx.String(ServerTag), APIServerTagDefault(ctx.App.Version))
+	if err != nil {
+		return usageErrorf("cube server upgrade: %v", err)
+	}
+
+	// the new container gets the settings of the server config rather than
+	// the old container config, which holds the defaults of the old image
+	serverConfigFile := serverConfigLocation(cubeCtx)
+	serverConfig, err := util.ReadServerConfig(serverConfigFile)
+	if err != nil {
+		return fmt.Errorf("cube server upgrade: %v", err)
+	}
+	env, err := util.ParseEnv(serverConfig.Env)
+	if err != nil {
+		return usageErrorf("cube server upgrade: %v", err)
+	}
+	port := APIServerPortDefault
+	if len(current.Ports) > 0 {
+		port = strconv.Itoa(int(current.Ports[0].PrivatePort))
+	}
+	containerConfig, err := apiServerContainerConfig(image, port, env, serverConfig)
+	if err != nil {
+		return err
+	}
+
+	prsMap, err := registryCredentials(ctx, cubeCtx, image)
+	if err != nil {
+		return fmt.Errorf("cube server upgrade: %v", err)
+	}
+
+	var probe func() error
+	if probeURL := apiServerURL(cubeCtx.DockerHost, current.Ports); probeURL != "" {
+		probe = func() error {
+			result, err := util.ProbeHTTP(probeURL, serverProbeTimeout)
+			if err != nil {
+				return err
+			}
+			if !result.Healthy() {
+				return fmt.Errorf("%s responded %d", probeURL, result.StatusCode)
+			}
+			return nil
+		}
+	}
+
+	upgraded, err := docker.UpgradeContainer(context, dClient, cubeCtx.ContainerName, containerConfig, prsMap, ctx.Duration(Timeout), probe)
+	if err != nil {
+		return prefixError("cube server upgrade", err)
+	}
+	if upgraded {
+		addChange("container %s upgraded to image %s", cubeCtx.ContainerName, image)
+	}
+
+	// keep the image so that the next run does not go back to the old one
+	if serverConfig.Image != image {
+		serverConfig.Image = image
+		if err := util.WriteServerConfig(serverConfig, serverConfigFile); err != nil {
+			return fmt.Errorf("cube server upgrade: %v", err)
+		}
+		addChange("server config saved to %s", serverConfigFile)
+	}
+	return nil
+}
+
+// apiServerURL returns the url probed for the api-server health, it is empty
+// when the api-server port is not published.
+func apiServerURL(dockerHost string, ports []types.Port) string {
+	for _, port := range ports {
+		if port.PublicPort == 0 {
+			continue
+		}
+
+		host := port.IP
+		if host == "" || net.ParseIP(host).IsUnspecified() {
+			host = "127.0.0.1"
+			// the published port is on the remote docker host
+			if u, err := url.Parse(dockerHost); err == nil && u.Scheme == "tcp" && u.Hostname() != "" {
+				host = u.Hostname()
+			}
+		}
+		return "http://" + net.JoinHostPort(host, strconv.Itoa(int(port.PublicPort))) + APIServerVersionPath
+	}
+	return ""
 }
 
 func serverStop(ctx *cli.Context) error {
+	cubeCtx, err := currentContext(ctx)
+	if err != nil {
+		return err
+	}
+	addTarget(cubeCtx.ContainerName)
+
 	context := context.Background()
 
-	dClient, err := docker.NewClient(context, docker.SystemDockerSock)
+	dClient, err := docker.NewClient(context, cubeCtx.DockerHost)
 	if err != nil {
 		return err
 	}
 
-	return docker.StopContainer(context, dClient, APIServerContainerName)
+	stopped, err := docker.StopContainer(context, dClient, cubeCtx.ContainerName)
+	if err != nil {
+		return err
+	}
+	if stopped {
+		addChange("container %s stopped", cubeCtx.ContainerName)
+	}
+	return nil
 }
 
 func serverRm(ctx *cli.Context) error {
+	cubeCtx, err := currentContext(ctx)
+	if err != nil {
+		return err
+	}
+	addTarget(cubeCtx.ContainerName)
+
+	context := context.Background()
+
+	dClient, err := docker.NewClient(context, cubeCtx.DockerHost)
+	if err != nil {
+		return err
+	}
+
+	removed, err := docker.RemoveContainer(context, dClient, cubeCtx.ContainerName)
+	if err != nil {
+		return err
+	}
+	if removed {
+		addChange("container %s removed", cubeCtx.ContainerName)
+	}
+	return nil
+}
+
+func serverLogs(ctx *cli.Context) error {
+	tail := ctx.String(LogsTail)
+	if tail != "all" {
+		if lines, err := strconv.Atoi(tail); err != nil || lines < 0 {
+			return usageErrorf("cube server logs: %s must be a number of lines or all", LogsTail)
+		}
+	}
+	if ctx.Bool(LogsFollow) && structuredOutput() {
+		return usageErrorf("cube server logs: --%s can not be used with --%s", LogsFollow, OutputFlag)
+	}
+
+	cubeCtx, err := currentContext(ctx)
+	if err != nil {
+		return err
+	}
+	addTarget(cubeCtx.ContainerName)
+
 	context := context.Background()
 
-	dClient, err := docker.NewClient(context, docker.SystemDockerSock)
+	dClient, err := docker.NewClient(context, cubeCtx.DockerHost)
 	if err != nil {
 		return err
 	}
 
-	return docker.RemoveContainer(context, dClient, APIServerContainerName)
+	options := types.ContainerLogsOptions{
+		Follow:     ctx.Bool(LogsFollow),
+		Tail:       tail,
+		Since:      ctx.String(LogsSince),
+		Timestamps: ctx.Bool(LogsTimestamps),
+	}
+	if !structuredOutput() {
+		return docker.LogsContainer(context, dClient, cubeCtx.ContainerName, options, os.Stdout, os.Stderr)
+	}
+
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	if err := docker.LogsContainer(context, dClient, cubeCtx.ContainerName, options, stdout, stderr); err != nil {
+		return err
+	}
+	setData(LogsOutput{
+		Stdout: stdout.String(),
+		Stderr: stderr.String(),
+	})
+	return nil
 }
 
 func serverStatus(ctx *cli.Context) error {
+	cubeCtx, err := currentContext(ctx)
+	if err != nil {
+		return err
+	}
+	addTarget(cubeCtx.ContainerName)
+
 	context := context.Background()
 
-	dClient, err := docker.NewClient(context, docker.SystemDockerSock)
+	dClient, err := docker.NewClient(context, cubeCtx.DockerHost)
 	if err != nil {
 		return err
 	}
 
-	container, err := docker.StatusContainer(context, dClient, APIServerContainerName)
+	output, err := serverHealth(context, dClient, cubeCtx)
 	if err != nil {
 		return err
 	}
 
+	if ctx.Bool(ServerWait) {
+		deadline := time.Now().Add(ctx.Duration(Timeout))
+		for output.Health != ServerHealthy && time.Now().Add(serverWaitPollInterval).Before(deadline) {
+			logrus.Debugf("cube server status: api-server is %s, waiting", output.Health)
+			time.Sleep(serverWaitPollInterval)
+			if output, err = serverHealth(context, dClient, cubeCtx); err != nil {
+				return err
+			}
+		}
+	}
+
 	writer := table.NewServerWriter([][]string{
 		{"CONTAINER ID", "{{.ID | id}}"},
 		{"IMAGE", "{{.Image}}"},
 		{"COMMAND", "{{.Command | cmd}}"},
 		{"CREATED", "{{.Created | ago}}"},
-		{"STATUS", "{{.Status}}"},
+		{"STATUS", "{{.Status}}", table.PresetNarrow},
 		{"PORTS", "{{.Ports | port}}"},
-		{"NAMES", "{{.Names | name}}"},
+		{"NAMES", "{{.Names | name}}", table.PresetNarrow},
+		{"HEALTH", `{{.Health}}{{if and .Health (ne .Health "healthy")}} (restarts {{.RestartCount}}, exit code {{.ExitCode}}){{end}}`, table.PresetNarrow},
+		{"LATENCY", "{{.Latency | latency}}"},
+		{"VERSION", "{{.Version}}"},
+		{"MOUNTS", "{{.Mounts | mounts}}", table.PresetWide},
 	}, ctx)
-	defer writer.Close()
+	collectOutput(writer)
 
-	if container.ID != "" {
-		writer.Write(container)
+	if output.ID != "" {
+		writer.Write(output)
 	}
 
-	return writer.Err()
+	if err := writer.Close(); err != nil {
+		return err
+	}
+	if ctx.Bool(ServerWait) && output.Health != ServerHealthy {
+		if output.ID == "" {
+			return notFoundErrorf("cube server status: api-server container %s not found after %v", cubeCtx.ContainerName, ctx.Duration(Timeout))
+		}
+		return unavailableErrorf("cube server status: api-server is %s after %v", output.Health, ctx.Duration(Timeout))
+	}
+	return nil
+}
+
+// serverHealth inspects the api-server container and probes its published
+// port, the output has an empty ID when the container does not exist.
+func serverHealth(ctx context.Context, dClient *client.Client, cubeCtx *util.Context) (*ServerOutput, error) {
+	container, err := docker.StatusContainer(ctx, dClient, cubeCtx.ContainerName)
+	if err != nil {
+		return nil, err
+	}
+
+	output := &ServerOutput{Container: *container}
+	if container.ID == "" {
+		return output, nil
+	}
+
+	info, err := dClient.ContainerInspect(ctx, container.ID)
+	if err != nil {
+		return nil, err
+	}
+	output.RestartCount = info.RestartCount
+	if info.State != nil {
+		output.ExitCode = info.State.ExitCode
+		if !info.State.Running || info.State.Restarting {
+			output.Health = ServerUnhealthy
+			return output, nil
+		}
+	}
+
+	probeURL := apiServerURL(cubeCtx.DockerHost, container.Ports)
+	if probeURL == "" {
+		output.Health = ServerUnreachable
+		return output, nil
+	}
+
+	result, err := util.ProbeHTTP(probeURL, serverProbeTimeout)
+	if err != nil {
+		logrus.Debugf("cube server status: probe %s error: %v", probeURL, err)
+		output.Health = ServerUnreachable
+		return output, nil
+	}
+
+	output.Reachable = true
+	output.Latency = result.Latency
+	output.Version = result.Version
+	output.Health = ServerHealthy
+	if !result.Healthy() || (info.State != nil && info.State.Health != nil && info.State.Health.Status == types.Unhealthy) {
+		output.Health = ServerUnhealthy
+	}
+	return output, nil
 }
@@ -2,11 +2,15 @@ package docker
 
 import (
 	"context"
+	"net/http"
+	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/coreos/go-semver/semver"
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/client"
+	"github.com/docker/go-connections/tlsconfig"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 )
@@ -16,7 +20,12 @@ func NewClient(ctx context.Context, host string) (*client.Client, error) {
 		return nil, errors.New("engine client host param is empty")
 	}
 
-	dClient, err := client.NewClient(host, EngineAPIVersion, nil, nil)
+	httpClient, err := newTLSClient(host)
+	if err != nil {
+		return nil, err
+	}
+
+	dClient, err := client.NewClient(host, EngineAPIVersion, httpClient, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -29,6 +38,43 @@ func NewClient(ctx context.Context, host string) (*client.Client, error) {
 	return dClient, nil
 }
 
+// newTLSClient returns the http client securing a tcp docker host as the
+// docker cli does with DOCKER_TLS, DOCKER_TLS_VERIFY and DOCKER_CERT_PATH, or
+// nil when tls is not asked for. The server certificate is verified against
+// the ca.pem of the cert path unless DOCKER_TLS is set without DOCKER_TLS_VERIFY.
+func newTLSClient(host string) (*http.Client, error) {
+	verify := os.Getenv(DockerTLSVerifyEnv) != ""
+	insecure := os.Getenv(DockerTLSEnv) != "" && !verify
+	certPath := os.Getenv(DockerCertPathEnv)
+	if (!verify && !insecure && certPath == "") || !strings.HasPrefix(host, "tcp://") {
+		return nil, nil
+	}
+	if certPath == "" {
+		certPath = filepath.Dir(DockerConfigFile())
+	}
+
+	options := tlsconfig.Options{
+		CAFile:             filepath.Join(certPath, "ca.pem"),
+		CertFile:           filepath.Join(certPath, "cert.pem"),
+		KeyFile:            filepath.Join(certPath, "key.pem"),
+		InsecureSkipVerify: insecure,
+	}
+	if insecure {
+		options.CAFile = ""
+	}
+	tlsConfig, err := tlsconfig.Client(options)
+	if err != nil {
+		return nil, errors.Wrapf(err, "load docker tls certificates from %s", certPath)
+	}
+
+	return &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: tlsConfig,
+		},
+		CheckRedirect: client.CheckRedirect,
+	}, nil
+}
+
 func CheckEngineVersion(ctx context.Context, dClient *client.Client) error {
 	info, err := dClient.Info(ctx)
 	if err != nil {
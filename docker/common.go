@@ -1,6 +1,10 @@
 package docker
 
-import "time"
+import (
+	"os"
+	"strings"
+	"time"
+)
 
 var (
 	EngineAPIVersion     = "1.24"
@@ -13,6 +17,34 @@ var (
 	SystemDockerSock = "unix:///var/run/system-docker.sock"
 )
 
+const (
+	DockerHostEnv      = "DOCKER_HOST"
+	DockerTLSEnv       = "DOCKER_TLS"
+	DockerTLSVerifyEnv = "DOCKER_TLS_VERIFY"
+	DockerCertPathEnv  = "DOCKER_CERT_PATH"
+)
+
+// DefaultHost returns the docker host of DOCKER_HOST, or the system-docker
+// socket of RancherOS falling back to the docker socket.
+func DefaultHost() string {
+	if host := os.Getenv(DockerHostEnv); host != "" {
+		return host
+	}
+	return ResolveHost(SystemDockerSock)
+}
+
+// ResolveHost falls back to the docker socket when the host is the
+// system-docker socket and it is missing, as on any other os than RancherOS.
+func ResolveHost(host string) string {
+	if host != SystemDockerSock {
+		return host
+	}
+	if _, err := os.Stat(strings.TrimPrefix(SystemDockerSock, "unix://")); err != nil {
+		return EngineDefaultSock
+	}
+	return host
+}
+
 type PrivateRegistry struct {
 	// URL for the registry
 	URL string `yaml:"url" json:"url,omitempty"`
@@ -0,0 +1,99 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"regexp"
+
+	ref "github.com/docker/distribution/reference"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/client"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// ManagedLabel marks the containers created by cube, the others are never touched
+	ManagedLabel      = "io.cube.managed"
+	managedLabelValue = "true"
+
+	// apiServerImageName is the image of the api-server containers created
+	// before the managed label, they are adopted whatever their registry or tag
+	apiServerImageName = "cube-apiserver"
+)
+
+// NotFoundError reports a container which does not exist.
+type NotFoundError struct {
+	Name string
+}
+
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("container %s not found", e.Name)
+}
+
+// NotManagedError reports a container of the looked up name not created by cube.
+type NotManagedError struct {
+	Name string
+}
+
+func (e *NotManagedError) Error() string {
+	return fmt.Sprintf("container %s is not managed by cube, remove or rename it, or use another container name", e.Name)
+}
+
+// FindContainer returns the container named exactly containerName, or nil
+// when there is none. A container of that name not created by cube is an
+// error rather than a match, unless it runs the api-server image.
+func FindContainer(ctx context.Context, dClient *client.Client, containerName string) (*types.Container, error) {
+	args := filters.NewArgs()
+	// docker matches the name filter as a regular expression anywhere in the name
+	args.Add("name", "^/"+regexp.QuoteMeta(containerName)+"$")
+
+	containers, err := dClient.ContainerList(ctx, types.ContainerListOptions{All: true, Filters: args})
+	if err != nil {
+		logrus.Errorf("list docker containers error: %v", err)
+		return nil, err
+	}
+
+	for _, c := range containers {
+		for _, name := range c.Names {
+			if name != "/"+containerName {
+				continue
+			}
+			if !IsManaged(c) {
+				if !isAPIServerImage(c.Image) {
+					return nil, &NotManagedError{Name: containerName}
+				}
+				logrus.Debugf("adopt container %s running image %s without the %s label", containerName, c.Image, ManagedLabel)
+			}
+			container := c
+			return &container, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// IsManaged reports whether the container carries the cube managed label.
+func IsManaged(c types.Container) bool {
+	return c.Labels[ManagedLabel] == managedLabelValue
+}
+
+// isAPIServerImage reports whether the image is a cube api-server image of
+// any registry, including mirrors, and any tag.
+func isAPIServerImage(image string) bool {
+	named, err := ref.ParseNormalizedNamed(image)
+	if err != nil {
+		return false
+	}
+	return path.Base(ref.Path(named)) == apiServerImageName
+}
+
+// setManagedLabel labels the container config as created by cube.
+func setManagedLabel(labels map[string]string) map[string]string {
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	labels[ManagedLabel] = managedLabelValue
+	return labels
+}
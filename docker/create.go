@@ -2,83 +2,237 @@ package docker
 
 import (
 	"context"
+	"fmt"
+	"net"
 	"os"
+	"sort"
 	"strings"
 
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
 	"github.com/docker/docker/api/types/network"
 	"github.com/docker/docker/client"
+	"github.com/docker/go-connections/nat"
 	"github.com/sirupsen/logrus"
 )
 
-func CreateOrRestart(ctx context.Context, dClient *client.Client, config *container.Config, hostConfig *container.HostConfig, networkingConfig *network.NetworkingConfig, containerName string) error {
-	containers, err := dClient.ContainerList(ctx, types.ContainerListOptions{All: true})
+// The ways CreateOrRestart runs the container.
+const (
+	ContainerCreated   = "created"
+	ContainerRecreated = "recreated"
+	ContainerRestarted = "restarted"
+)
+
+// CreateOrRestart runs the container, an existing container is restarted when
+// its spec matches the requested one and recreated otherwise. It returns how
+// the container was run.
+func CreateOrRestart(ctx context.Context, dClient *client.Client, config *container.Config, hostConfig *container.HostConfig, networkingConfig *network.NetworkingConfig, containerName string, prsMap map[string]PrivateRegistry, forceRecreate bool) (string, error) {
+	found, err := FindContainer(ctx, dClient, containerName)
 	if err != nil {
-		logrus.Errorf("list docker containers error: %v", err)
-		return err
+		return "", err
 	}
+	config.Labels = setManagedLabel(config.Labels)
+
+	if found != nil {
+		containerID := found.ID
+		existing, err := dClient.ContainerInspect(ctx, containerID)
+		if err != nil {
+			logrus.Errorf("inspect container %s error: %v", containerID, err)
+			return "", err
+		}
 
-	isFound := false
-	containerID := ""
-	// check that the container with the specified name exists
-	for _, c := range containers {
-		for _, name := range c.Names {
-			if strings.Contains(name, containerName) {
-				isFound = true
-				containerID = c.ID
-				break
+		imageEnv := []string{}
+		if image, _, err := dClient.ImageInspectWithRaw(ctx, existing.Image); err == nil && image.Config != nil {
+			imageEnv = image.Config.Env
+		}
+
+		changes := DiffContainerSpec(existing, imageEnv, config, hostConfig)
+		if !IsManaged(*found) {
+			// recreate the containers adopted from before the managed label to label them
+			changes = append(changes, fmt.Sprintf("label %s: none -> %s", ManagedLabel, managedLabelValue))
+		}
+		if len(changes) == 0 && !forceRecreate {
+			// restart the container
+			if err := dClient.ContainerRestart(ctx, containerID, &ContainerDefaultTimeout); err != nil {
+				logrus.Errorf("restart container %s error: %v", containerID, err)
+				return "", err
 			}
+			logBindings(ctx, dClient, containerID)
+			return ContainerRestarted, nil
+		}
+
+		if len(changes) == 0 {
+			logrus.Infof("recreate container %s as requested", containerName)
 		}
-		if isFound {
-			break
+		for _, change := range changes {
+			logrus.Infof("recreate container %s, %s", containerName, change)
 		}
-	}
 
-	if isFound && containerID != "" {
-		// restart the container
-		if err := dClient.ContainerRestart(ctx, containerID, &ContainerDefaultTimeout); err != nil {
-			logrus.Errorf("restart container %s error: %v", containerID, err)
-			return err
+		// pull before removing, a failed pull keeps the existing container running
+		if err := pullContainerImage(ctx, dClient, config.Image, prsMap); err != nil {
+			return "", err
 		}
-		logrus.Infof("server run at 0.0.0.0:9600")
-		return nil
+
+		if err := dClient.ContainerRemove(ctx, containerID, types.ContainerRemoveOptions{Force: true}); err != nil {
+			logrus.Errorf("remove container %s error: %v", containerID, err)
+			return "", err
+		}
+	} else if err := pullContainerImage(ctx, dClient, config.Image, prsMap); err != nil {
+		return "", err
 	}
 
-	// pull container image
-	hostName, err := os.Hostname()
+	// create container
+	resp, err := dClient.ContainerCreate(ctx, config, hostConfig, networkingConfig, containerName)
 	if err != nil {
-		logrus.Errorf("get host name error: %v", err)
-		return err
+		logrus.Errorf("create container %s error: %v", containerName, err)
+		return "", err
 	}
 
-	// TODO: currently only support docker.io, this need to be enhanced to support multiple registry
-	parseMap := make(map[string]PrivateRegistry)
-	for _, pr := range parseMap {
-		if pr.URL == "" {
-			pr.URL = EngineRegistryURL
-		}
-		parseMap[pr.URL] = pr
+	if err := dClient.ContainerStart(ctx, resp.ID, types.ContainerStartOptions{}); err != nil {
+		logrus.Errorf("start container %s error: %v", containerName, err)
+		return "", err
+	}
+
+	logBindings(ctx, dClient, resp.ID)
+
+	if found != nil {
+		return ContainerRecreated, nil
 	}
-	err = UseLocalOrPull(ctx, dClient, hostName, config.Image, parseMap)
+	return ContainerCreated, nil
+}
+
+func pullContainerImage(ctx context.Context, dClient *client.Client, image string, prsMap map[string]PrivateRegistry) error {
+	hostName, err := os.Hostname()
 	if err != nil {
-		logrus.Errorf("use local or pull image %s error: %v", config.Image, err)
+		logrus.Errorf("get host name error: %v", err)
 		return err
 	}
 
-	// create container
-	resp, err := dClient.ContainerCreate(ctx, config, hostConfig, networkingConfig, containerName)
+	err = UseLocalOrPull(ctx, dClient, hostName, image, prsMap)
 	if err != nil {
-		logrus.Errorf("create container %s error: %v", containerID, err)
+		logrus.Errorf("use local or pull image %s error: %v", image, err)
 		return err
 	}
+	return nil
+}
 
-	if err := dClient.ContainerStart(ctx, resp.ID, types.ContainerStartOptions{}); err != nil {
-		logrus.Errorf("start container %s error: %v", containerID, err)
-		return err
+// DiffContainerSpec describes how the requested container spec differs from
+// the existing container, only the fields set by cube are compared. The
+// environment variables coming from the image are left out.
+func DiffContainerSpec(existing types.ContainerJSON, imageEnv []string, config *container.Config, hostConfig *container.HostConfig) []string {
+	changes := []string{}
+	diff := func(field, from, to string) {
+		if from == "" {
+			from = "none"
+		}
+		if to == "" {
+			to = "none"
+		}
+		if from != to {
+			changes = append(changes, fmt.Sprintf("%s: %s -> %s", field, from, to))
+		}
 	}
 
-	logrus.Infof("server run at 0.0.0.0:9600")
+	existingConfig := existing.Config
+	if existingConfig == nil {
+		existingConfig = &container.Config{}
+	}
+	existingHostConfig := &container.HostConfig{}
+	if existing.ContainerJSONBase != nil && existing.HostConfig != nil {
+		existingHostConfig = existing.HostConfig
+	}
 
-	return nil
+	diff("image", existingConfig.Image, config.Image)
+	diff("command", strings.Join(existingConfig.Cmd, " "), strings.Join(config.Cmd, " "))
+
+	existingEnv := []string{}
+	for _, env := range existingConfig.Env {
+		if !containsString(imageEnv, env) || containsString(config.Env, env) {
+			existingEnv = append(existingEnv, env)
+		}
+	}
+	diff("env", strings.Join(sortedStrings(existingEnv), " "), strings.Join(sortedStrings(config.Env), " "))
+
+	diff("ports", strings.Join(formatPortMap(existingHostConfig.PortBindings), " "), strings.Join(formatPortMap(hostConfig.PortBindings), " "))
+	diff("mounts", strings.Join(formatMounts(existingHostConfig.Mounts), " "), strings.Join(formatMounts(hostConfig.Mounts), " "))
+	diff("restart policy", existingHostConfig.RestartPolicy.Name, hostConfig.RestartPolicy.Name)
+
+	return changes
+}
+
+func containsString(slice []string, s string) bool {
+	for _, item := range slice {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedStrings(slice []string) []string {
+	sorted := append([]string{}, slice...)
+	sort.Strings(sorted)
+	return sorted
+}
+
+func formatPortMap(bindings nat.PortMap) []string {
+	ports := []string{}
+	for port, portBindings := range bindings {
+		for _, binding := range portBindings {
+			hostIP := binding.HostIP
+			if hostIP == "" {
+				hostIP = "0.0.0.0"
+			}
+			ports = append(ports, net.JoinHostPort(hostIP, binding.HostPort)+"->"+string(port))
+		}
+	}
+	sort.Strings(ports)
+	return ports
+}
+
+func formatMounts(mounts []mount.Mount) []string {
+	result := []string{}
+	for _, m := range mounts {
+		formatted := string(m.Type) + ":" + m.Source + ":" + m.Target
+		if m.ReadOnly {
+			formatted += ":ro"
+		}
+		result = append(result, formatted)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// logBindings logs the host addresses the container is published on, as
+// docker reports them rather than as requested.
+func logBindings(ctx context.Context, dClient *client.Client, containerID string) {
+	info, err := dClient.ContainerInspect(ctx, containerID)
+	if err != nil || info.HostConfig == nil {
+		logrus.Warnf("inspect container %s error: %v", containerID, err)
+		return
+	}
+
+	addresses := FormatPortBindings(info.HostConfig.PortBindings)
+	if len(addresses) == 0 {
+		logrus.Infof("server run without published ports")
+		return
+	}
+	logrus.Infof("server run at %s", strings.Join(addresses, ", "))
+}
+
+// FormatPortBindings returns the sorted host addresses of the port bindings.
+func FormatPortBindings(bindings nat.PortMap) []string {
+	addresses := []string{}
+	for _, portBindings := range bindings {
+		for _, binding := range portBindings {
+			hostIP := binding.HostIP
+			if hostIP == "" {
+				hostIP = "0.0.0.0"
+			}
+			addresses = append(addresses, net.JoinHostPort(hostIP, binding.HostPort))
+		}
+	}
+	sort.Strings(addresses)
+	return addresses
 }
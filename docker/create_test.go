@@ -0,0 +1,131 @@
+package docker
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/go-connections/nat"
+)
+
+func TestDiffContainerSpec(t *testing.T) {
+	imageEnv := []string{"PATH=/usr/local/bin:/usr/bin", "LANG=C.UTF-8"}
+	config := &container.Config{
+		Image: "cnrancher/cube-apiserver:v0.1.0",
+		Cmd:   []string{"serve", "--listen-addr=0.0.0.0:9600"},
+		Env:   []string{"FOO=bar", "A=1"},
+	}
+	hostConfig := &container.HostConfig{
+		RestartPolicy: container.RestartPolicy{Name: "always"},
+		PortBindings: nat.PortMap{
+			"9600/tcp": []nat.PortBinding{{HostIP: "127.0.0.1", HostPort: "9600"}, {HostIP: "::1", HostPort: "9600"}},
+			"9700/tcp": []nat.PortBinding{{HostPort: "9700"}},
+		},
+		Mounts: []mount.Mount{
+			{Type: mount.TypeBind, Source: "/opt/cube/kube_config.yml", Target: "/var/lib/rancher/cube/kube_config.yml", ReadOnly: true},
+			{Type: mount.TypeBind, Source: "/etc", Target: "/host-etc"},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		existing types.ContainerJSON
+		changes  []string
+	}{
+		{
+			name: "env differing only by the image env",
+			existing: existingContainer(func(c *container.Config, h *container.HostConfig) {
+				c.Env = []string{"A=1", "PATH=/usr/local/bin:/usr/bin", "FOO=bar", "LANG=C.UTF-8"}
+			}),
+			changes: []string{},
+		},
+		{
+			name: "env overriding the image env",
+			existing: existingContainer(func(c *container.Config, h *container.HostConfig) {
+				c.Env = []string{"A=1", "FOO=bar", "LANG=C.UTF-8"}
+			}),
+			changes: []string{},
+		},
+		{
+			name: "env changed",
+			existing: existingContainer(func(c *container.Config, h *container.HostConfig) {
+				c.Env = []string{"A=2", "FOO=bar", "PATH=/usr/local/bin:/usr/bin"}
+			}),
+			changes: []string{"env: A=2 FOO=bar -> A=1 FOO=bar"},
+		},
+		{
+			name: "port and mount ordering",
+			existing: existingContainer(func(c *container.Config, h *container.HostConfig) {
+				h.PortBindings = nat.PortMap{
+					"9700/tcp": []nat.PortBinding{{HostIP: "0.0.0.0", HostPort: "9700"}},
+					"9600/tcp": []nat.PortBinding{{HostIP: "::1", HostPort: "9600"}, {HostIP: "127.0.0.1", HostPort: "9600"}},
+				}
+				h.Mounts = []mount.Mount{h.Mounts[1], h.Mounts[0]}
+			}),
+			changes: []string{},
+		},
+		{
+			name: "image, port and mount changed",
+			existing: existingContainer(func(c *container.Config, h *container.HostConfig) {
+				c.Image = "cnrancher/cube-apiserver:v0.0.9"
+				h.PortBindings = nat.PortMap{"9600/tcp": []nat.PortBinding{{HostIP: "127.0.0.1", HostPort: "9600"}}}
+				h.Mounts = []mount.Mount{{Type: mount.TypeBind, Source: "/etc", Target: "/host-etc"}}
+			}),
+			changes: []string{
+				"image: cnrancher/cube-apiserver:v0.0.9 -> cnrancher/cube-apiserver:v0.1.0",
+				"ports: 127.0.0.1:9600->9600/tcp -> 0.0.0.0:9700->9700/tcp 127.0.0.1:9600->9600/tcp [::1]:9600->9600/tcp",
+				"mounts: bind:/etc:/host-etc -> bind:/etc:/host-etc bind:/opt/cube/kube_config.yml:/var/lib/rancher/cube/kube_config.yml:ro",
+			},
+		},
+		{
+			name:     "container without config",
+			existing: types.ContainerJSON{},
+			changes: []string{
+				"image: none -> cnrancher/cube-apiserver:v0.1.0",
+				"command: none -> serve --listen-addr=0.0.0.0:9600",
+				"env: none -> A=1 FOO=bar",
+				"ports: none -> 0.0.0.0:9700->9700/tcp 127.0.0.1:9600->9600/tcp [::1]:9600->9600/tcp",
+				"mounts: none -> bind:/etc:/host-etc bind:/opt/cube/kube_config.yml:/var/lib/rancher/cube/kube_config.yml:ro",
+				"restart policy: none -> always",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			changes := DiffContainerSpec(test.existing, imageEnv, config, hostConfig)
+			if !reflect.DeepEqual(changes, test.changes) {
+				t.Errorf("got changes %q, want %q", changes, test.changes)
+			}
+		})
+	}
+}
+
+// existingContainer returns a container created with the spec of
+// TestDiffContainerSpec, changed by fn.
+func existingContainer(fn func(*container.Config, *container.HostConfig)) types.ContainerJSON {
+	config := &container.Config{
+		Image: "cnrancher/cube-apiserver:v0.1.0",
+		Cmd:   []string{"serve", "--listen-addr=0.0.0.0:9600"},
+		Env:   []string{"FOO=bar", "A=1", "PATH=/usr/local/bin:/usr/bin", "LANG=C.UTF-8"},
+	}
+	hostConfig := &container.HostConfig{
+		RestartPolicy: container.RestartPolicy{Name: "always"},
+		PortBindings: nat.PortMap{
+			"9600/tcp": []nat.PortBinding{{HostIP: "127.0.0.1", HostPort: "9600"}, {HostIP: "::1", HostPort: "9600"}},
+			"9700/tcp": []nat.PortBinding{{HostPort: "9700"}},
+		},
+		Mounts: []mount.Mount{
+			{Type: mount.TypeBind, Source: "/opt/cube/kube_config.yml", Target: "/var/lib/rancher/cube/kube_config.yml", ReadOnly: true},
+			{Type: mount.TypeBind, Source: "/etc", Target: "/host-etc"},
+		},
+	}
+	fn(config, hostConfig)
+
+	return types.ContainerJSON{
+		ContainerJSONBase: &types.ContainerJSONBase{HostConfig: hostConfig},
+		Config:            config,
+	}
+}
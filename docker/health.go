@@ -0,0 +1,105 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	ContainerHealthTimeoutDefault = 2 * time.Minute
+	// ContainerStablePeriod is how long a container without health check must
+	// keep running to be considered healthy
+	ContainerStablePeriod = 5 * time.Second
+
+	containerHealthPollInterval = time.Second
+)
+
+// WaitContainerHealthy waits for the container to pass its docker health check,
+// or to keep running for ContainerStablePeriod when it has none, and for the
+// optional probe to succeed. A restart of the container fails the wait at once.
+func WaitContainerHealthy(ctx context.Context, dClient *client.Client, containerID string, timeout time.Duration, probe func() error) error {
+	deadline := time.Now().Add(timeout)
+
+	initial, err := dClient.ContainerInspect(ctx, containerID)
+	if err != nil {
+		return err
+	}
+
+	var lastErr error
+	for {
+		info, err := dClient.ContainerInspect(ctx, containerID)
+		if err != nil {
+			return err
+		}
+		lastErr = checkContainerHealthy(info, initial.RestartCount)
+		if _, failed := lastErr.(containerFailedError); failed {
+			return lastErr
+		}
+		if lastErr == nil && probe != nil {
+			lastErr = probe()
+		}
+		if lastErr == nil {
+			return nil
+		}
+
+		if time.Now().Add(containerHealthPollInterval).After(deadline) {
+			return fmt.Errorf("container %s is not healthy after %v: %v", shortID(containerID), timeout, lastErr)
+		}
+		logrus.Debugf("waiting for container %s: %v", shortID(containerID), lastErr)
+		time.Sleep(containerHealthPollInterval)
+	}
+}
+
+// containerFailedError reports a container which can no longer become healthy.
+type containerFailedError struct {
+	error
+}
+
+func checkContainerHealthy(info types.ContainerJSON, restartCount int) error {
+	if info.ContainerJSONBase == nil || info.State == nil {
+		return fmt.Errorf("container state is unknown")
+	}
+
+	state := info.State
+	if info.RestartCount > restartCount || state.Dead || (!state.Running && state.FinishedAt != "" && state.FinishedAt != "0001-01-01T00:00:00Z") {
+		if state.Error != "" {
+			return containerFailedError{fmt.Errorf("container exited with code %d: %s", state.ExitCode, state.Error)}
+		}
+		return containerFailedError{fmt.Errorf("container exited with code %d", state.ExitCode)}
+	}
+	if !state.Running || state.Restarting {
+		return fmt.Errorf("container is %s", state.Status)
+	}
+
+	if state.Health != nil {
+		switch state.Health.Status {
+		case types.Healthy:
+			return nil
+		case types.Unhealthy:
+			return containerFailedError{fmt.Errorf("container health check failed %d times", state.Health.FailingStreak)}
+		default:
+			return fmt.Errorf("container health is %s", state.Health.Status)
+		}
+	}
+
+	startedAt, err := time.Parse(time.RFC3339Nano, state.StartedAt)
+	if err != nil {
+		return fmt.Errorf("container start time %s is invalid: %v", state.StartedAt, err)
+	}
+	if running := time.Since(startedAt); running < ContainerStablePeriod {
+		return fmt.Errorf("container is running for %v only", running.Round(time.Second))
+	}
+	return nil
+}
+
+func shortID(containerID string) string {
+	if len(containerID) > 12 {
+		return containerID[:12]
+	}
+	return containerID
+}
@@ -0,0 +1,62 @@
+package docker
+
+import (
+	"fmt"
+	"strings"
+
+	ref "github.com/docker/distribution/reference"
+)
+
+// ImageName assembles the image reference from an optional registry prefix,
+// the image repository and the tag. The default tag only applies to an image
+// without tag or digest, and an explicit tag conflicts with them.
+func ImageName(registry, image, tag, defaultTag string) (string, error) {
+	name := image
+	if registry != "" {
+		name = strings.TrimSuffix(registry, "/") + "/" + image
+	}
+
+	named, err := ref.ParseNormalizedNamed(name)
+	if err != nil {
+		return "", fmt.Errorf("invalid image %s: %v", name, err)
+	}
+
+	if !ref.IsNameOnly(named) {
+		if tag != "" {
+			return "", fmt.Errorf("image %s already has a tag or digest", name)
+		}
+		return ref.FamiliarString(named), nil
+	}
+
+	if tag == "" {
+		tag = defaultTag
+	}
+	if tag == "" {
+		return ref.FamiliarString(ref.TagNameOnly(named)), nil
+	}
+
+	tagged, err := ref.WithTag(named, tag)
+	if err != nil {
+		return "", fmt.Errorf("invalid image tag %s: %v", tag, err)
+	}
+	return ref.FamiliarString(tagged), nil
+}
+
+// ImageDomain returns the registry domain of the image, docker.io for the
+// images without one.
+func ImageDomain(image string) (string, error) {
+	named, err := ref.ParseNormalizedNamed(image)
+	if err != nil {
+		return "", fmt.Errorf("invalid image %s: %v", image, err)
+	}
+	return ref.Domain(named), nil
+}
+
+// ImageRepository returns the image without its tag or digest.
+func ImageRepository(image string) (string, error) {
+	named, err := ref.ParseNormalizedNamed(image)
+	if err != nil {
+		return "", fmt.Errorf("invalid image %s: %v", image, err)
+	}
+	return ref.FamiliarName(named), nil
+}
@@ -0,0 +1,128 @@
+package docker
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestImageName(t *testing.T) {
+	digest := "sha256:" + strings.Repeat("a", 64)
+
+	tests := []struct {
+		name       string
+		registry   string
+		image      string
+		tag        string
+		defaultTag string
+		want       string
+		wantErr    string
+	}{
+		{
+			name:       "default registry with the default tag",
+			image:      "cnrancher/cube-apiserver",
+			defaultTag: "v0.1.0",
+			want:       "cnrancher/cube-apiserver:v0.1.0",
+		},
+		{
+			name:  "default registry without a default tag",
+			image: "cnrancher/cube-apiserver",
+			want:  "cnrancher/cube-apiserver:latest",
+		},
+		{
+			name:       "explicit tag",
+			image:      "cnrancher/cube-apiserver",
+			tag:        "v0.2.0",
+			defaultTag: "v0.1.0",
+			want:       "cnrancher/cube-apiserver:v0.2.0",
+		},
+		{
+			name:       "custom registry",
+			registry:   "registry.example.com:5000/",
+			image:      "cnrancher/cube-apiserver",
+			defaultTag: "v0.1.0",
+			want:       "registry.example.com:5000/cnrancher/cube-apiserver:v0.1.0",
+		},
+		{
+			name:       "image tag wins over the default tag",
+			image:      "cnrancher/cube-apiserver:dev",
+			defaultTag: "v0.1.0",
+			want:       "cnrancher/cube-apiserver:dev",
+		},
+		{
+			name:       "digest",
+			registry:   "registry.example.com",
+			image:      "cnrancher/cube-apiserver@" + digest,
+			defaultTag: "v0.1.0",
+			want:       "registry.example.com/cnrancher/cube-apiserver@" + digest,
+		},
+		{
+			name:    "tag with a digest",
+			image:   "cnrancher/cube-apiserver@" + digest,
+			tag:     "v0.2.0",
+			wantErr: "already has a tag or digest",
+		},
+		{
+			name:    "tag with a tagged image",
+			image:   "cnrancher/cube-apiserver:dev",
+			tag:     "v0.2.0",
+			wantErr: "already has a tag or digest",
+		},
+		{
+			name:    "invalid tag",
+			image:   "cnrancher/cube-apiserver",
+			tag:     "v0.2.0+build",
+			wantErr: "invalid image tag",
+		},
+		{
+			name:    "invalid image",
+			image:   "CNRancher/cube-apiserver",
+			wantErr: "invalid image",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			image, err := ImageName(test.registry, test.image, test.tag, test.defaultTag)
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Fatalf("got image %s and error %v, want %q", image, err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if image != test.want {
+				t.Errorf("got image %s, want %s", image, test.want)
+			}
+		})
+	}
+}
+
+func TestImageDomain(t *testing.T) {
+	tests := []struct {
+		image string
+		want  string
+	}{
+		{image: "cnrancher/cube-apiserver:v0.1.0", want: "docker.io"},
+		{image: "busybox", want: "docker.io"},
+		{image: "registry.example.com:5000/cnrancher/cube-apiserver:v0.1.0", want: "registry.example.com:5000"},
+		{image: "localhost/cube-apiserver@sha256:" + strings.Repeat("a", 64), want: "localhost"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.image, func(t *testing.T) {
+			domain, err := ImageDomain(test.image)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if domain != test.want {
+				t.Errorf("got domain %s, want %s", domain, test.want)
+			}
+		})
+	}
+
+	if _, err := ImageDomain("CNRancher/cube-apiserver"); err == nil {
+		t.Errorf("got no error for an invalid image")
+	}
+}
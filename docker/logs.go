@@ -0,0 +1,45 @@
+package docker
+
+import (
+	"context"
+	"io"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/sirupsen/logrus"
+)
+
+// LogsContainer writes the container logs to stdout and stderr, the log
+// stream is demultiplexed unless the container runs with a tty.
+func LogsContainer(ctx context.Context, dClient *client.Client, containerName string, options types.ContainerLogsOptions, stdout, stderr io.Writer) error {
+	container, err := StatusContainer(ctx, dClient, containerName)
+	if err != nil {
+		return err
+	}
+	if container.ID == "" {
+		return &NotFoundError{Name: containerName}
+	}
+
+	info, err := dClient.ContainerInspect(ctx, container.ID)
+	if err != nil {
+		logrus.Errorf("inspect container %s error: %v", container.ID, err)
+		return err
+	}
+
+	options.ShowStdout = true
+	options.ShowStderr = true
+	reader, err := dClient.ContainerLogs(ctx, container.ID, options)
+	if err != nil {
+		logrus.Errorf("get container %s logs error: %v", container.ID, err)
+		return err
+	}
+	defer reader.Close()
+
+	if info.Config != nil && info.Config.Tty {
+		_, err = io.Copy(stdout, reader)
+		return err
+	}
+	_, err = stdcopy.StdCopy(stdout, stderr, reader)
+	return err
+}
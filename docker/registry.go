@@ -0,0 +1,109 @@
+package docker
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	DockerConfigEnv  = "DOCKER_CONFIG"
+	DockerConfigName = "config.json"
+
+	dockerHubIndex = "index.docker.io"
+	dockerHubHost  = "registry-1.docker.io"
+)
+
+type dockerConfigFile struct {
+	Auths map[string]dockerAuthConfig `json:"auths"`
+}
+
+type dockerAuthConfig struct {
+	Auth     string `json:"auth,omitempty"`
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+}
+
+// DockerConfigFile returns the docker cli config location, $DOCKER_CONFIG/config.json
+// or ~/.docker/config.json.
+func DockerConfigFile() string {
+	if dir := os.Getenv(DockerConfigEnv); dir != "" {
+		return filepath.Join(dir, DockerConfigName)
+	}
+
+	home := os.Getenv("HOME")
+	if home == "" {
+		if u, err := user.Current(); err == nil {
+			home = u.HomeDir
+		}
+	}
+	return filepath.Join(home, ".docker", DockerConfigName)
+}
+
+// RegistryDomain normalizes a registry url, e.g. https://index.docker.io/v1/,
+// to the domain of the images hosted by the registry.
+func RegistryDomain(url string) string {
+	domain := url
+	if index := strings.Index(domain, "://"); index >= 0 {
+		domain = domain[index+3:]
+	}
+	if index := strings.Index(domain, "/"); index >= 0 {
+		domain = domain[:index]
+	}
+
+	switch domain {
+	case "", dockerHubIndex, dockerHubHost:
+		return EngineRegistryURL
+	}
+	return domain
+}
+
+// ReadDockerConfigAuths returns the registry credentials stored by docker login,
+// keyed by registry domain. A missing config yields no credentials, the
+// credentials kept by credential helpers are not read.
+func ReadDockerConfigAuths(filename string) (map[string]PrivateRegistry, error) {
+	prsMap := map[string]PrivateRegistry{}
+
+	data, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return prsMap, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	config := dockerConfigFile{}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("parse docker config %s: %v", filename, err)
+	}
+
+	for url, auth := range config.Auths {
+		pr := PrivateRegistry{
+			URL:      RegistryDomain(url),
+			User:     auth.Username,
+			Password: auth.Password,
+		}
+		if auth.Auth != "" {
+			decoded, err := base64.StdEncoding.DecodeString(auth.Auth)
+			if err != nil {
+				return nil, fmt.Errorf("parse docker config %s: invalid auth of %s: %v", filename, url, err)
+			}
+			parts := strings.SplitN(string(decoded), ":", 2)
+			if len(parts) != 2 {
+				return nil, fmt.Errorf("parse docker config %s: invalid auth of %s", filename, url)
+			}
+			pr.User, pr.Password = parts[0], parts[1]
+		}
+		if pr.User == "" {
+			continue
+		}
+		prsMap[pr.URL] = pr
+	}
+
+	return prsMap, nil
+}
@@ -2,46 +2,28 @@ package docker
 
 import (
 	"context"
-	"strings"
 
-	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/client"
 	"github.com/sirupsen/logrus"
 )
 
-func StopContainer(ctx context.Context, dClient *client.Client, containerName string) error {
-	containers, err := dClient.ContainerList(ctx, types.ContainerListOptions{All: true})
+// StopContainer stops the container, it reports whether the container was found.
+func StopContainer(ctx context.Context, dClient *client.Client, containerName string) (bool, error) {
+	container, err := FindContainer(ctx, dClient, containerName)
 	if err != nil {
-		logrus.Errorf("list docker containers error: %v", err)
-		return err
+		return false, err
 	}
 
-	isFound := false
-	containerID := ""
-	// check that the container with the specified name exists
-	for _, c := range containers {
-		for _, name := range c.Names {
-			if strings.Contains(name, containerName) {
-				isFound = true
-				containerID = c.ID
-				break
-			}
-		}
-		if isFound {
-			break
-		}
-	}
-
-	if isFound && containerID != "" {
+	if container != nil {
 		// stop the container
-		if err := dClient.ContainerStop(ctx, containerID, &ContainerDefaultTimeout); err != nil {
-			logrus.Errorf("stop container %s error: %v", containerID, err)
-			return err
+		if err := dClient.ContainerStop(ctx, container.ID, &ContainerDefaultTimeout); err != nil {
+			logrus.Errorf("stop container %s error: %v", container.ID, err)
+			return false, err
 		}
-		return nil
+		return true, nil
 	}
 
-	logrus.Warnf("container not found")
+	logrus.Warnf("container %s not found", containerName)
 
-	return nil
+	return false, nil
 }
@@ -0,0 +1,106 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+	"github.com/sirupsen/logrus"
+)
+
+const previousContainerSuffix = "-previous"
+
+// UpgradeContainer replaces the container by one created from the config,
+// the host config of the container is kept. The previous container is stopped and kept
+// aside until the new one is healthy, and it is restored otherwise. It
+// reports whether the container was replaced.
+func UpgradeContainer(ctx context.Context, dClient *client.Client, containerName string, config *container.Config, prsMap map[string]PrivateRegistry, timeout time.Duration, probe func() error) (bool, error) {
+	image := config.Image
+	current, err := StatusContainer(ctx, dClient, containerName)
+	if err != nil {
+		return false, err
+	}
+	if current.ID == "" {
+		return false, &NotFoundError{Name: containerName}
+	}
+
+	previous, err := dClient.ContainerInspect(ctx, current.ID)
+	if err != nil {
+		logrus.Errorf("inspect container %s error: %v", current.ID, err)
+		return false, err
+	}
+	if previous.Config == nil || previous.HostConfig == nil {
+		return false, fmt.Errorf("container %s has no config", containerName)
+	}
+	if previous.Config.Image == image {
+		logrus.Infof("container %s already runs image %s", containerName, image)
+		return false, nil
+	}
+
+	if err := pullContainerImage(ctx, dClient, image, prsMap); err != nil {
+		return false, err
+	}
+
+	config.Labels = setManagedLabel(config.Labels)
+
+	previousName := containerName + previousContainerSuffix
+	logrus.Infof("stop container %s running image %s", containerName, previous.Config.Image)
+	if err := dClient.ContainerStop(ctx, previous.ID, &ContainerDefaultTimeout); err != nil {
+		logrus.Errorf("stop container %s error: %v", previous.ID, err)
+		return false, err
+	}
+	if err := dClient.ContainerRename(ctx, previous.ID, previousName); err != nil {
+		logrus.Errorf("rename container %s error: %v", previous.ID, err)
+		return false, restartContainer(ctx, dClient, previous.ID, err)
+	}
+
+	logrus.Infof("start container %s running image %s", containerName, image)
+	resp, err := dClient.ContainerCreate(ctx, config, previous.HostConfig, nil, containerName)
+	if err != nil {
+		logrus.Errorf("create container %s error: %v", containerName, err)
+		return false, rollbackContainer(ctx, dClient, "", previous.ID, containerName, err)
+	}
+	if err := dClient.ContainerStart(ctx, resp.ID, types.ContainerStartOptions{}); err != nil {
+		logrus.Errorf("start container %s error: %v", containerName, err)
+		return false, rollbackContainer(ctx, dClient, resp.ID, previous.ID, containerName, err)
+	}
+
+	logrus.Infof("wait for container %s to become healthy", containerName)
+	if err := WaitContainerHealthy(ctx, dClient, resp.ID, timeout, probe); err != nil {
+		return false, rollbackContainer(ctx, dClient, resp.ID, previous.ID, containerName, err)
+	}
+
+	if err := dClient.ContainerRemove(ctx, previous.ID, types.ContainerRemoveOptions{}); err != nil {
+		logrus.Warnf("remove previous container %s error: %v", previousName, err)
+	}
+	logrus.Infof("container %s upgraded to image %s", containerName, image)
+	logBindings(ctx, dClient, resp.ID)
+	return true, nil
+}
+
+// rollbackContainer removes the failed container and brings the previous one
+// back under its name, the cause is returned along with any rollback failure.
+func rollbackContainer(ctx context.Context, dClient *client.Client, failedID, previousID, containerName string, cause error) error {
+	logrus.Warnf("upgrade container %s failed, rolling back: %v", containerName, cause)
+
+	if failedID != "" {
+		if err := dClient.ContainerRemove(ctx, failedID, types.ContainerRemoveOptions{Force: true}); err != nil {
+			return fmt.Errorf("%v, and remove the failed container %s: %v", cause, shortID(failedID), err)
+		}
+	}
+	if err := dClient.ContainerRename(ctx, previousID, containerName); err != nil {
+		return fmt.Errorf("%v, and rename the previous container %s back: %v", cause, shortID(previousID), err)
+	}
+	return restartContainer(ctx, dClient, previousID, cause)
+}
+
+func restartContainer(ctx context.Context, dClient *client.Client, containerID string, cause error) error {
+	if err := dClient.ContainerStart(ctx, containerID, types.ContainerStartOptions{}); err != nil {
+		return fmt.Errorf("%v, and restart the previous container %s: %v", cause, shortID(containerID), err)
+	}
+	logrus.Infof("previous container %s restored", shortID(containerID))
+	return fmt.Errorf("upgrade rolled back: %v", cause)
+}
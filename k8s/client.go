@@ -1,46 +1,98 @@
 package k8s
 
 import (
-	"github.com/sirupsen/logrus"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 )
 
 var (
-	clientGenerator *ClientGenerator
+	clientGenerators     = map[clientKey]*ClientGenerator{}
+	clientGeneratorsLock sync.Mutex
 )
 
+// clientKey identifies the cached clientsets, a clientset is only shared by
+// the callers of the same kube config and timeout.
+type clientKey struct {
+	kubeConfig string
+	timeout    time.Duration
+}
+
 type ClientGenerator struct {
 	Clientset kubernetes.Clientset
 }
 
-func NewClientGenerator(kubeConfig string) *ClientGenerator {
-	if clientGenerator == nil {
-		var config *rest.Config
-		var err error
+// NewClientGenerator builds the kubernetes clientset from the kube config,
+// the in cluster config is used when kubeConfig is empty. A zero timeout
+// leaves the api calls unbounded. The clientsets are cached per kube config
+// and timeout.
+func NewClientGenerator(kubeConfig string, timeout time.Duration) (*ClientGenerator, error) {
+	clientGeneratorsLock.Lock()
+	defer clientGeneratorsLock.Unlock()
 
-		if kubeConfig == "" {
-			config, err = rest.InClusterConfig()
-			if err != nil {
-				logrus.Fatalf("generate config failed: %v", err)
-			}
-		}
+	key := clientKey{kubeConfig: kubeConfig, timeout: timeout}
+	if clientGenerator, ok := clientGenerators[key]; ok {
+		return clientGenerator, nil
+	}
 
-		config, err = clientcmd.BuildConfigFromFlags("", kubeConfig)
+	var config *rest.Config
+	var err error
+
+	if kubeConfig == "" {
+		config, err = rest.InClusterConfig()
 		if err != nil {
-			logrus.Fatalf("generate config failed: %v", err)
+			return nil, errors.Wrap(err, "generate in cluster config failed")
+		}
+	} else {
+		if _, err := os.Stat(kubeConfig); err != nil {
+			return nil, errors.Wrapf(err, "kubernetes config %s is not available", kubeConfig)
 		}
 
-		clientset, err := kubernetes.NewForConfig(config)
+		config, err = clientcmd.BuildConfigFromFlags("", kubeConfig)
 		if err != nil {
-			logrus.Fatalf("generate clientset failed: %v", err)
+			return nil, errors.Wrap(err, "generate config failed")
 		}
+	}
+	config.Timeout = timeout
 
-		clientGenerator = &ClientGenerator{
-			Clientset: *clientset,
-		}
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, errors.Wrap(err, "generate clientset failed")
 	}
 
-	return clientGenerator
+	clientGenerator := &ClientGenerator{
+		Clientset: *clientset,
+	}
+	clientGenerators[key] = clientGenerator
+	return clientGenerator, nil
+}
+
+// ValidateKubeConfig checks that the kube config parses and reaches its
+// cluster, it returns the kubernetes version of the cluster.
+func ValidateKubeConfig(kubeConfig string, timeout time.Duration) (string, error) {
+	if _, err := clientcmd.LoadFromFile(kubeConfig); err != nil {
+		return "", errors.Wrapf(err, "kubernetes config %s is invalid", kubeConfig)
+	}
+
+	config, err := clientcmd.BuildConfigFromFlags("", kubeConfig)
+	if err != nil {
+		return "", errors.Wrapf(err, "kubernetes config %s is invalid", kubeConfig)
+	}
+	config.Timeout = timeout
+
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return "", errors.Wrap(err, "generate clientset failed")
+	}
+
+	version, err := clientset.Discovery().ServerVersion()
+	if err != nil {
+		return "", errors.Wrapf(err, "kubernetes config %s can not reach its cluster", kubeConfig)
+	}
+	return version.GitVersion, nil
 }
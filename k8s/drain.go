@@ -0,0 +1,190 @@
+package k8s
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"k8s.io/api/core/v1"
+	policy "k8s.io/api/policy/v1beta1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+const (
+	mirrorPodAnnotation = "kubernetes.io/config.mirror"
+	daemonSetKind       = "DaemonSet"
+
+	DrainTimeoutDefault = 5 * time.Minute
+
+	evictionRetryInterval = 5 * time.Second
+	podDeletePollInterval = 2 * time.Second
+)
+
+// DrainOptions controls how the pods are evicted from a node.
+type DrainOptions struct {
+	// DeleteUnmanaged evicts the pods not managed by a controller, they are
+	// lost for good
+	DeleteUnmanaged bool
+	// DeleteEmptyDir evicts the pods using emptyDir volumes, their data is lost
+	DeleteEmptyDir bool
+	// GracePeriod overrides the pod termination grace period when not negative
+	GracePeriod int
+	// Timeout bounds the whole drain, zero uses DrainTimeoutDefault
+	Timeout time.Duration
+}
+
+// CordonNode marks the node unschedulable.
+func (c *ClientGenerator) CordonNode(name string) error {
+	node, err := c.Clientset.CoreV1().Nodes().Get(name, metaV1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	if node.Spec.Unschedulable {
+		return nil
+	}
+
+	node.Spec.Unschedulable = true
+	_, err = c.Clientset.CoreV1().Nodes().Update(node)
+	return err
+}
+
+// DrainNode evicts the pods running on the node through the eviction api, so
+// that pod disruption budgets are respected, and waits for them to be gone.
+// Mirror pods and daemon set pods are left in place. The pods not managed by
+// a controller and the pods using emptyDir volumes are only evicted when the
+// options allow it.
+func (c *ClientGenerator) DrainNode(name string, options DrainOptions) error {
+	timeout := options.Timeout
+	if timeout <= 0 {
+		timeout = DrainTimeoutDefault
+	}
+	deadline := time.Now().Add(timeout)
+
+	podList, err := c.Clientset.CoreV1().Pods(metaV1.NamespaceAll).List(metaV1.ListOptions{
+		FieldSelector: fields.SelectorFromSet(fields.Set{"spec.nodeName": name}).String(),
+	})
+	if err != nil {
+		return err
+	}
+
+	pods := []v1.Pod{}
+	unmanaged := []string{}
+	emptyDir := []string{}
+	for _, pod := range podList.Items {
+		if _, ok := pod.Annotations[mirrorPodAnnotation]; ok {
+			continue
+		}
+		if pod.Status.Phase == v1.PodSucceeded || pod.Status.Phase == v1.PodFailed {
+			pods = append(pods, pod)
+			continue
+		}
+
+		controller := metaV1.GetControllerOf(&pod)
+		if controller != nil && controller.Kind == daemonSetKind {
+			continue
+		}
+		if controller == nil {
+			unmanaged = append(unmanaged, pod.Namespace+"/"+pod.Name)
+		}
+		if hasEmptyDir(pod) {
+			emptyDir = append(emptyDir, pod.Namespace+"/"+pod.Name)
+		}
+		pods = append(pods, pod)
+	}
+
+	if len(unmanaged) > 0 && !options.DeleteUnmanaged {
+		return fmt.Errorf("pods not managed by a controller would be lost, delete unmanaged pods to evict them: %s", strings.Join(unmanaged, ", "))
+	}
+	if len(emptyDir) > 0 && !options.DeleteEmptyDir {
+		return fmt.Errorf("pods using emptyDir volumes would lose their data, delete emptyDir data to evict them: %s", strings.Join(emptyDir, ", "))
+	}
+
+	for _, pod := range pods {
+		if err := c.evictPod(pod, options.GracePeriod, deadline); err != nil {
+			return errors.Wrapf(err, "evict pod %s/%s", pod.Namespace, pod.Name)
+		}
+	}
+
+	return c.waitForPodsDeleted(pods, deadline)
+}
+
+func hasEmptyDir(pod v1.Pod) bool {
+	for _, volume := range pod.Spec.Volumes {
+		if volume.EmptyDir != nil {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *ClientGenerator) evictPod(pod v1.Pod, gracePeriod int, deadline time.Time) error {
+	deleteOptions := &metaV1.DeleteOptions{}
+	if gracePeriod >= 0 {
+		gracePeriodSeconds := int64(gracePeriod)
+		deleteOptions.GracePeriodSeconds = &gracePeriodSeconds
+	}
+
+	eviction := &policy.Eviction{
+		ObjectMeta: metaV1.ObjectMeta{
+			Name:      pod.Name,
+			Namespace: pod.Namespace,
+		},
+		DeleteOptions: deleteOptions,
+	}
+
+	for {
+		err := c.Clientset.PolicyV1beta1().Evictions(pod.Namespace).Evict(eviction)
+		switch {
+		case err == nil, apierrors.IsNotFound(err):
+			return nil
+		case apierrors.IsTooManyRequests(err):
+			// a pod disruption budget blocks the eviction for now
+			if time.Now().Add(evictionRetryInterval).After(deadline) {
+				return errors.Wrap(err, "timed out waiting for the pod disruption budget")
+			}
+			logrus.Infof("pod %s/%s eviction is blocked by a disruption budget, retrying", pod.Namespace, pod.Name)
+			time.Sleep(evictionRetryInterval)
+		default:
+			return err
+		}
+	}
+}
+
+func (c *ClientGenerator) waitForPodsDeleted(pods []v1.Pod, deadline time.Time) error {
+	pending := pods
+	err := wait.PollImmediate(podDeletePollInterval, time.Until(deadline), func() (bool, error) {
+		left := []v1.Pod{}
+		for _, pod := range pending {
+			current, err := c.Clientset.CoreV1().Pods(pod.Namespace).Get(pod.Name, metaV1.GetOptions{})
+			if apierrors.IsNotFound(err) || (err == nil && current.UID != pod.UID) {
+				logrus.Infof("pod %s/%s evicted", pod.Namespace, pod.Name)
+				continue
+			}
+			if err != nil {
+				return false, err
+			}
+			left = append(left, pod)
+		}
+		pending = left
+		return len(pending) == 0, nil
+	})
+	if err == wait.ErrWaitTimeout {
+		return fmt.Errorf("timed out waiting for %d pods to be evicted", len(pending))
+	}
+	return err
+}
+
+// DeleteNode removes the node object, a missing node is not an error.
+func (c *ClientGenerator) DeleteNode(name string) error {
+	err := c.Clientset.CoreV1().Nodes().Delete(name, &metaV1.DeleteOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
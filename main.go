@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	"github.com/cnrancher/cube-cli/cmd"
+	"github.com/cnrancher/cube-cli/util"
 
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
@@ -16,7 +17,7 @@ var VERSION = "v0.0.0-dev"
 
 func main() {
 	if err := mainErr(); err != nil {
-		logrus.Fatal(err)
+		cmd.Exit(err)
 	}
 }
 
@@ -30,7 +31,12 @@ func mainErr() error {
 		if c.GlobalBool("debug") {
 			logrus.SetLevel(logrus.DebugLevel)
 		}
-		return nil
+		util.SetDataDir(c.GlobalString("data-dir"))
+		if c.GlobalString(cmd.OutputFlag) != "" {
+			// keep the usage messages out of the printed result
+			c.App.Writer = os.Stderr
+		}
+		return cmd.SetOutput(c.GlobalString(cmd.OutputFlag))
 	}
 	app.Flags = []cli.Flag{
 		cli.BoolFlag{
@@ -38,12 +44,34 @@ func mainErr() error {
 			Usage:  "enable debug logging level",
 			EnvVar: "RANCHER_DEBUG",
 		},
+		cli.StringFlag{
+			Name:   "data-dir",
+			Value:  util.DataDirDefault,
+			Usage:  "specify the directory where cube config files are stored",
+			EnvVar: util.DataDirEnv,
+		},
+		cli.StringFlag{
+			Name:   "context",
+			Usage:  "specify the cluster context, default to the context selected by 'cube context use'",
+			EnvVar: util.ContextEnv,
+		},
+		cli.StringFlag{
+			Name:   "docker-host",
+			Usage:  "specify the docker host running the api-server, default to the context docker host, DOCKER_HOST or the system-docker socket falling back to the docker socket",
+			EnvVar: util.DockerHostEnv,
+		},
+		cli.StringFlag{
+			Name:  cmd.OutputFlag + ", o",
+			Usage: "print the command result as 'json' or 'yaml', with the target, the changes made, the exit code and the error message",
+		},
 	}
 
 	app.Commands = []cli.Command{
 		cmd.ServerCommand(),
 		cmd.NodeCommand(),
 		cmd.RKECommand(),
+		cmd.ContextCommand(),
+		cmd.ConfigCommand(),
 		cmd.PromptCommand(),
 	}
 
@@ -52,7 +80,7 @@ func mainErr() error {
 	parsed, err := parseArgs(os.Args)
 	if err != nil {
 		logrus.Error(err)
-		os.Exit(1)
+		os.Exit(cmd.ExitUsage)
 	}
 
 	return app.Run(parsed)
@@ -0,0 +1,88 @@
+package util
+
+import (
+	"io/ioutil"
+	"os"
+
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	ContextFileName    = "contexts.yml"
+	ContextEnv         = "CUBE_CONTEXT"
+	DockerHostEnv      = "CUBE_DOCKER_HOST"
+	DefaultContextName = "default"
+)
+
+// Context describes one RancherCUBE cluster managed by the cli.
+type Context struct {
+	Name          string `yaml:"name" json:"name"`
+	RKEConfig     string `yaml:"rke_config" json:"rkeConfig"`
+	KubeConfig    string `yaml:"kube_config" json:"kubeConfig"`
+	DockerHost    string `yaml:"docker_host" json:"dockerHost"`
+	ContainerName string `yaml:"container_name" json:"containerName"`
+}
+
+// ContextConfig is the content of the local context file.
+type ContextConfig struct {
+	Current  string    `yaml:"current,omitempty" json:"current,omitempty"`
+	Contexts []Context `yaml:"contexts,omitempty" json:"contexts,omitempty"`
+}
+
+// ContextFile returns the context file location under the data directory.
+func ContextFile() string {
+	return DataPath(ContextFileName)
+}
+
+// ReadContextConfig reads the context file, a missing file yields an empty config.
+func ReadContextConfig(filename string) (*ContextConfig, error) {
+	config := &ContextConfig{}
+
+	bytes, err := ioutil.ReadFile(filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return config, nil
+		}
+		return nil, err
+	}
+
+	err = yaml.Unmarshal(bytes, config)
+	if err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+func WriteContextConfig(config *ContextConfig, filename string) error {
+	bytes, err := yaml.Marshal(config)
+	if err != nil {
+		return err
+	}
+
+	return WriteFileAtomic(filename, bytes, 0640)
+}
+
+// Get returns the context with the given name.
+func (c *ContextConfig) Get(name string) (*Context, bool) {
+	for i := range c.Contexts {
+		if c.Contexts[i].Name == name {
+			return &c.Contexts[i], true
+		}
+	}
+	return nil, false
+}
+
+// Remove deletes the context with the given name, returns false if not found.
+func (c *ContextConfig) Remove(name string) bool {
+	for i := range c.Contexts {
+		if c.Contexts[i].Name == name {
+			c.Contexts = append(c.Contexts[:i], c.Contexts[i+1:]...)
+			if c.Current == name {
+				c.Current = ""
+			}
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,100 @@
+package util
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+const (
+	DiffContextLines = 3
+)
+
+type diffLine struct {
+	op   byte
+	text string
+}
+
+// LineDiff returns a unified style diff of the two texts, an empty string
+// means the texts are equal.
+func LineDiff(fromName, toName, from, to string) string {
+	a := splitLines(from)
+	b := splitLines(to)
+
+	// longest common subsequence table, lcs[i][j] covers a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	lines := []diffLine{}
+	changed := false
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			lines = append(lines, diffLine{' ', a[i]})
+			i++
+			j++
+		case j < len(b) && (i == len(a) || lcs[i][j+1] > lcs[i+1][j]):
+			lines = append(lines, diffLine{'+', b[j]})
+			changed = true
+			j++
+		default:
+			lines = append(lines, diffLine{'-', a[i]})
+			changed = true
+			i++
+		}
+	}
+
+	if !changed {
+		return ""
+	}
+
+	buf := bytes.Buffer{}
+	fmt.Fprintf(&buf, "--- %s\n+++ %s\n", fromName, toName)
+
+	skipped := false
+	for index, line := range lines {
+		if line.op == ' ' && !nearChange(lines, index) {
+			skipped = true
+			continue
+		}
+		if skipped || index == 0 {
+			buf.WriteString("@@\n")
+			skipped = false
+		}
+		buf.WriteByte(line.op)
+		buf.WriteString(line.text)
+		buf.WriteString("\n")
+	}
+
+	return buf.String()
+}
+
+func nearChange(lines []diffLine, index int) bool {
+	for i := index - DiffContextLines; i <= index+DiffContextLines; i++ {
+		if i >= 0 && i < len(lines) && lines[i].op != ' ' {
+			return true
+		}
+	}
+	return false
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return []string{}
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
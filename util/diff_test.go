@@ -0,0 +1,61 @@
+package util
+
+import (
+	"testing"
+)
+
+func TestLineDiff(t *testing.T) {
+	tests := []struct {
+		name string
+		from string
+		to   string
+		want string
+	}{
+		{
+			name: "empty",
+			want: "",
+		},
+		{
+			name: "identical",
+			from: "nodes:\n- address: 10.0.0.1\n",
+			to:   "nodes:\n- address: 10.0.0.1\n",
+			want: "",
+		},
+		{
+			name: "trailing newline only",
+			from: "a\nb\n",
+			to:   "a\nb",
+			want: "",
+		},
+		{
+			name: "from empty",
+			to:   "a\nb\n",
+			want: "--- 1\n+++ 2\n@@\n+a\n+b\n",
+		},
+		{
+			name: "to empty",
+			from: "a\n",
+			want: "--- 1\n+++ 2\n@@\n-a\n",
+		},
+		{
+			name: "changed line with context",
+			from: "1\n2\n3\n4\n5\n6\n7\n8\n9\n",
+			to:   "1\n2\n3\n4\n5\nsix\n7\n8\n9\n",
+			want: "--- 1\n+++ 2\n@@\n 3\n 4\n 5\n-6\n+six\n 7\n 8\n 9\n",
+		},
+		{
+			name: "distant changes",
+			from: "a\n1\n2\n3\n4\n5\n6\n7\nb\n",
+			to:   "A\n1\n2\n3\n4\n5\n6\n7\nB\n",
+			want: "--- 1\n+++ 2\n@@\n-a\n+A\n 1\n 2\n 3\n@@\n 5\n 6\n 7\n-b\n+B\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if diff := LineDiff("1", "2", test.from, test.to); diff != test.want {
+				t.Errorf("got diff\n%q\nwant\n%q", diff, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package util
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+const (
+	BackupSuffix     = ".bak"
+	BackupTimeFormat = "20060102-150405.000"
+)
+
+// WriteFileAtomic writes the data to a temporary file in the same directory
+// and renames it into place, so that readers never see a partial file.
+func WriteFileAtomic(filename string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(filename)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(dir, "."+filepath.Base(filename)+".tmp")
+	if err != nil {
+		return err
+	}
+	// clean up the temporary file if anything below fails
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), perm); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), filename)
+}
+
+// BackupFile copies the file to a timestamped sibling and keeps at most limit
+// backups, a missing file is not an error and yields an empty backup name.
+func BackupFile(filename string, limit int) (string, error) {
+	src, err := os.Open(filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	defer src.Close()
+
+	info, err := src.Stat()
+	if err != nil {
+		return "", err
+	}
+
+	backup := filename + "." + time.Now().UTC().Format(BackupTimeFormat) + BackupSuffix
+	dst, err := os.OpenFile(backup, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, info.Mode().Perm())
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		return "", err
+	}
+	if err := dst.Close(); err != nil {
+		return "", err
+	}
+
+	return backup, pruneBackups(filename, limit)
+}
+
+func pruneBackups(filename string, limit int) error {
+	if limit <= 0 {
+		return nil
+	}
+
+	backups, err := filepath.Glob(filename + ".*" + BackupSuffix)
+	if err != nil {
+		return err
+	}
+	if len(backups) <= limit {
+		return nil
+	}
+
+	// the timestamp format sorts in creation order
+	sort.Strings(backups)
+	for _, backup := range backups[:len(backups)-limit] {
+		if err := os.Remove(backup); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
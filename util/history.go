@@ -0,0 +1,158 @@
+package util
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	HistoryDirSuffix = ".history"
+	HistoryIndexName = "index.yml"
+	InitialRevision  = "initial"
+)
+
+// Revision describes one recorded version of a config file.
+type Revision struct {
+	Revision  int       `yaml:"revision" json:"revision"`
+	Timestamp time.Time `yaml:"timestamp" json:"timestamp"`
+	Command   string    `yaml:"command" json:"command"`
+	User      string    `yaml:"user" json:"user"`
+}
+
+// History is the index of the recorded revisions of a config file.
+type History struct {
+	Revisions []Revision `yaml:"revisions,omitempty" json:"revisions,omitempty"`
+}
+
+// HistoryDir returns the directory holding the revisions of the file.
+func HistoryDir(filename string) string {
+	return filename + HistoryDirSuffix
+}
+
+func revisionFile(filename string, revision int) string {
+	return filepath.Join(HistoryDir(filename), fmt.Sprintf("%d.yml", revision))
+}
+
+// ReadHistory reads the revision index of the file, a file without history
+// yields an empty index.
+func ReadHistory(filename string) (*History, error) {
+	history := &History{}
+
+	bytes, err := ioutil.ReadFile(filepath.Join(HistoryDir(filename), HistoryIndexName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return history, nil
+		}
+		return nil, err
+	}
+
+	err = yaml.Unmarshal(bytes, history)
+	if err != nil {
+		return nil, err
+	}
+
+	return history, nil
+}
+
+// Get returns the revision with the given number.
+func (h *History) Get(revision int) (*Revision, bool) {
+	for i := range h.Revisions {
+		if h.Revisions[i].Revision == revision {
+			return &h.Revisions[i], true
+		}
+	}
+	return nil, false
+}
+
+// RevisionNotFoundError reports a revision missing from the history.
+type RevisionNotFoundError struct {
+	Revision int
+}
+
+func (e *RevisionNotFoundError) Error() string {
+	return fmt.Sprintf("revision %d not found", e.Revision)
+}
+
+// ReadRevision returns the file content recorded by the revision.
+func ReadRevision(filename string, revision int) ([]byte, error) {
+	history, err := ReadHistory(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := history.Get(revision); !ok {
+		return nil, &RevisionNotFoundError{Revision: revision}
+	}
+
+	return ioutil.ReadFile(revisionFile(filename, revision))
+}
+
+// InitHistory records the current content of the file as the first revision
+// when the file exists but has no history yet, so that the state before the
+// first recorded change can be restored.
+func InitHistory(filename, user string) error {
+	history, err := ReadHistory(filename)
+	if err != nil {
+		return err
+	}
+	if len(history.Revisions) > 0 {
+		return nil
+	}
+
+	if _, err := os.Stat(filename); err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	_, err = RecordRevision(filename, InitialRevision, user)
+	return err
+}
+
+// RecordRevision snapshots the current content of the file as a new revision,
+// callers should hold the LockFile of the filename.
+func RecordRevision(filename, command, user string) (*Revision, error) {
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	history, err := ReadHistory(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	next := 1
+	if len(history.Revisions) > 0 {
+		next = history.Revisions[len(history.Revisions)-1].Revision + 1
+	}
+
+	if err := WriteFileAtomic(revisionFile(filename, next), content, 0640); err != nil {
+		return nil, err
+	}
+
+	revision := Revision{
+		Revision:  next,
+		Timestamp: time.Now().UTC(),
+		Command:   command,
+		User:      user,
+	}
+	history.Revisions = append(history.Revisions, revision)
+
+	bytes, err := yaml.Marshal(history)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := WriteFileAtomic(filepath.Join(HistoryDir(filename), HistoryIndexName), bytes, 0640); err != nil {
+		return nil, err
+	}
+
+	return &revision, nil
+}
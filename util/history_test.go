@@ -0,0 +1,181 @@
+package util
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestRecordRevision(t *testing.T) {
+	tests := []struct {
+		name      string
+		contents  []string
+		initial   bool
+		revisions []int
+		commands  []string
+	}{
+		{
+			name:      "first revision",
+			contents:  []string{"nodes: []\n"},
+			revisions: []int{1},
+			commands:  []string{"cube node add 10.0.0.1"},
+		},
+		{
+			name:      "revisions numbered in order",
+			contents:  []string{"a\n", "b\n", "c\n"},
+			revisions: []int{1, 2, 3},
+			commands:  []string{"cube node add 10.0.0.1", "cube node add 10.0.0.1", "cube node add 10.0.0.1"},
+		},
+		{
+			name:      "initial revision of an existing file",
+			contents:  []string{"a\n"},
+			initial:   true,
+			revisions: []int{1, 2},
+			commands:  []string{InitialRevision, "cube node add 10.0.0.1"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := tempDir(t)
+			defer os.RemoveAll(dir)
+			filename := filepath.Join(dir, "rke_config.yml")
+
+			if test.initial {
+				writeFile(t, filename, "initial\n")
+				if err := InitHistory(filename, "rancher"); err != nil {
+					t.Fatalf("init history: %v", err)
+				}
+			}
+			for _, content := range test.contents {
+				writeFile(t, filename, content)
+				if _, err := RecordRevision(filename, "cube node add 10.0.0.1", "rancher"); err != nil {
+					t.Fatalf("record revision: %v", err)
+				}
+			}
+
+			history, err := ReadHistory(filename)
+			if err != nil {
+				t.Fatalf("read history: %v", err)
+			}
+			revisions, commands := []int{}, []string{}
+			for _, revision := range history.Revisions {
+				revisions = append(revisions, revision.Revision)
+				commands = append(commands, revision.Command)
+				if revision.User != "rancher" {
+					t.Errorf("revision %d recorded user %s, want rancher", revision.Revision, revision.User)
+				}
+			}
+			if !reflect.DeepEqual(revisions, test.revisions) || !reflect.DeepEqual(commands, test.commands) {
+				t.Fatalf("got revisions %v %q, want %v %q", revisions, commands, test.revisions, test.commands)
+			}
+
+			// the last revision holds the current content
+			last := test.revisions[len(test.revisions)-1]
+			content, err := ReadRevision(filename, last)
+			if err != nil {
+				t.Fatalf("read revision %d: %v", last, err)
+			}
+			if want := test.contents[len(test.contents)-1]; string(content) != want {
+				t.Errorf("got revision %d content %q, want %q", last, content, want)
+			}
+		})
+	}
+}
+
+func TestReadRevisionNotFound(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "rke_config.yml")
+
+	tests := []struct {
+		name     string
+		record   bool
+		revision int
+	}{
+		{name: "file without history", revision: 1},
+		{name: "revision after the last", record: true, revision: 2},
+		{name: "revision zero", record: true, revision: 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if test.record {
+				writeFile(t, filename, "a\n")
+				if _, err := RecordRevision(filename, "cube node add 10.0.0.1", "rancher"); err != nil {
+					t.Fatalf("record revision: %v", err)
+				}
+			}
+
+			_, err := ReadRevision(filename, test.revision)
+			notFound, ok := err.(*RevisionNotFoundError)
+			if !ok || notFound.Revision != test.revision {
+				t.Errorf("got error %v, want revision %d not found", err, test.revision)
+			}
+		})
+	}
+}
+
+func TestBackupFilePrune(t *testing.T) {
+	tests := []struct {
+		name    string
+		missing bool
+		backups []string
+		limit   int
+		kept    int
+	}{
+		{name: "missing file", missing: true, limit: 2, kept: 0},
+		{name: "below the limit", backups: []string{"20200101-000000.000"}, limit: 3, kept: 2},
+		{name: "oldest pruned", backups: []string{"20200101-000000.000", "20200102-000000.000", "20200103-000000.000"}, limit: 2, kept: 2},
+		{name: "no limit", backups: []string{"20200101-000000.000", "20200102-000000.000"}, limit: 0, kept: 3},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := tempDir(t)
+			defer os.RemoveAll(dir)
+			filename := filepath.Join(dir, "rke_config.yml")
+
+			for _, timestamp := range test.backups {
+				writeFile(t, filename+"."+timestamp+BackupSuffix, timestamp)
+			}
+			if !test.missing {
+				writeFile(t, filename, "current\n")
+			}
+
+			backup, err := BackupFile(filename, test.limit)
+			if err != nil {
+				t.Fatalf("backup: %v", err)
+			}
+
+			backups, err := filepath.Glob(filename + ".*" + BackupSuffix)
+			if err != nil {
+				t.Fatalf("glob: %v", err)
+			}
+			if len(backups) != test.kept {
+				t.Fatalf("got backups %v, want %d", backups, test.kept)
+			}
+			if backup != "" && backups[len(backups)-1] != backup {
+				t.Errorf("got backups %v, want the newest %s kept", backups, backup)
+			}
+		})
+	}
+}
+
+func tempDir(t *testing.T) string {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "cube-util-test")
+	if err != nil {
+		t.Fatalf("temp dir: %v", err)
+	}
+	return dir
+}
+
+func writeFile(t *testing.T, filename, content string) {
+	t.Helper()
+	if err := ioutil.WriteFile(filename, []byte(content), 0640); err != nil {
+		t.Fatalf("write %s: %v", filename, err)
+	}
+}
@@ -0,0 +1,474 @@
+package util
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/rancher/types/apis/management.cattle.io/v3"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	InventoryCSV  = "csv"
+	InventoryYAML = "yaml"
+	InventoryINI  = "ini"
+
+	inventoryAllGroup = "all"
+)
+
+// InventoryFormats are the supported node list formats.
+var InventoryFormats = []string{InventoryCSV, InventoryYAML, InventoryINI}
+
+// csvColumns are the csv header names, they match the rke config yaml keys.
+var csvColumns = []string{
+	"address",
+	"port",
+	"internal_address",
+	"role",
+	"hostname_override",
+	"user",
+	"docker_socket",
+	"ssh_agent_auth",
+	"ssh_key_path",
+	"labels",
+}
+
+// inventoryNodes is the yaml node list, the same layout as the rke config.
+type inventoryNodes struct {
+	Nodes []v3.RKEConfigNode `yaml:"nodes"`
+}
+
+// InventoryFormat guesses the node list format from the file extension,
+// files without a known extension are treated as ansible inventories.
+func InventoryFormat(filename string) string {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv":
+		return InventoryCSV
+	case ".yml", ".yaml", ".json":
+		return InventoryYAML
+	default:
+		return InventoryINI
+	}
+}
+
+// ParseInventory parses a csv, yaml or ansible ini node list.
+func ParseInventory(data []byte, format string) ([]v3.RKEConfigNode, error) {
+	switch format {
+	case InventoryCSV:
+		return parseCSVInventory(data)
+	case InventoryYAML:
+		return parseYAMLInventory(data)
+	case InventoryINI:
+		return parseINIInventory(data)
+	}
+	return nil, fmt.Errorf("unsupported inventory format %s, supported formats are %s", format, strings.Join(InventoryFormats, ","))
+}
+
+// FormatInventory writes the nodes as a csv, yaml or ansible ini node list.
+func FormatInventory(nodes []v3.RKEConfigNode, format string) ([]byte, error) {
+	switch format {
+	case InventoryCSV:
+		return formatCSVInventory(nodes)
+	case InventoryYAML:
+		public := make([]v3.RKEConfigNode, 0, len(nodes))
+		for _, node := range nodes {
+			public = append(public, WithoutSecrets(node))
+		}
+		return yaml.Marshal(inventoryNodes{Nodes: public})
+	case InventoryINI:
+		return formatINIInventory(nodes), nil
+	}
+	return nil, fmt.Errorf("unsupported inventory format %s, supported formats are %s", format, strings.Join(InventoryFormats, ","))
+}
+
+func parseYAMLInventory(data []byte) ([]v3.RKEConfigNode, error) {
+	// accept both a rke config like document and a bare node list, a
+	// document with an empty node list is an empty inventory
+	wrapped := struct {
+		Nodes *[]v3.RKEConfigNode `yaml:"nodes"`
+	}{}
+	if err := yaml.Unmarshal(data, &wrapped); err == nil && wrapped.Nodes != nil {
+		return *wrapped.Nodes, nil
+	}
+
+	nodes := []v3.RKEConfigNode{}
+	if err := yaml.Unmarshal(data, &nodes); err != nil {
+		return nil, fmt.Errorf("yaml inventory is neither a node list nor a document with nodes: %v", err)
+	}
+	return nodes, nil
+}
+
+func parseCSVInventory(data []byte) ([]v3.RKEConfigNode, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.Comment = '#'
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		if err == io.EOF {
+			return []v3.RKEConfigNode{}, nil
+		}
+		return nil, err
+	}
+	for i := range header {
+		header[i] = strings.ToLower(strings.TrimSpace(header[i]))
+		if !isCSVColumn(header[i]) {
+			return nil, fmt.Errorf("unknown csv column %s, supported columns are %s", header[i], strings.Join(csvColumns, ","))
+		}
+	}
+
+	nodes := []v3.RKEConfigNode{}
+	for row := 1; ; row++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		vars := map[string]string{}
+		for i, value := range record {
+			vars[header[i]] = strings.TrimSpace(value)
+		}
+
+		node := v3.RKEConfigNode{}
+		if err := setNodeVars(&node, vars); err != nil {
+			return nil, fmt.Errorf("csv row %d: %v", row, err)
+		}
+		nodes = append(nodes, node)
+	}
+
+	return nodes, nil
+}
+
+func isCSVColumn(name string) bool {
+	for _, column := range csvColumns {
+		if column == name {
+			return true
+		}
+	}
+	return false
+}
+
+func formatCSVInventory(nodes []v3.RKEConfigNode) ([]byte, error) {
+	buf := bytes.Buffer{}
+	writer := csv.NewWriter(&buf)
+
+	if err := writer.Write(csvColumns); err != nil {
+		return nil, err
+	}
+	for _, node := range nodes {
+		vars := nodeVars(node)
+		record := make([]string, len(csvColumns))
+		for i, column := range csvColumns {
+			record[i] = vars[column]
+		}
+		if err := writer.Write(record); err != nil {
+			return nil, err
+		}
+	}
+
+	writer.Flush()
+	return buf.Bytes(), writer.Error()
+}
+
+// ansibleVars maps the ansible connection variables to the rke config keys.
+var ansibleVars = map[string]string{
+	"ansible_host":                 "address",
+	"ansible_ssh_host":             "address",
+	"ansible_port":                 "port",
+	"ansible_ssh_port":             "port",
+	"ansible_user":                 "user",
+	"ansible_ssh_user":             "user",
+	"ansible_ssh_private_key_file": "ssh_key_path",
+}
+
+// iniExportVars are the ansible names used when writing an inventory.
+var iniExportVars = map[string]string{
+	"port":         "ansible_port",
+	"user":         "ansible_user",
+	"ssh_key_path": "ansible_ssh_private_key_file",
+}
+
+type iniHost struct {
+	alias  string
+	groups []string
+	vars   map[string]string
+}
+
+// parseINIInventory reads an ansible ini inventory, the etcd, controlplane
+// and worker groups give the node roles. The other groups only hold hosts and
+// variables referenced by the role groups, a host without a role is rejected.
+func parseINIInventory(data []byte) ([]v3.RKEConfigNode, error) {
+	hosts := []*iniHost{}
+	hostsByAlias := map[string]*iniHost{}
+	groupVars := map[string]map[string]string{}
+
+	group := inventoryAllGroup
+	isVars := false
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			group = strings.TrimSpace(line[1 : len(line)-1])
+			isVars = false
+			if strings.HasSuffix(group, ":vars") {
+				group = strings.TrimSuffix(group, ":vars")
+				isVars = true
+			} else if strings.HasSuffix(group, ":children") {
+				return nil, fmt.Errorf("ini line %d: group children are not supported", lineNumber)
+			}
+			continue
+		}
+
+		fields, err := splitINIFields(line)
+		if err != nil {
+			return nil, fmt.Errorf("ini line %d: %v", lineNumber, err)
+		}
+
+		if isVars {
+			if groupVars[group] == nil {
+				groupVars[group] = map[string]string{}
+			}
+			if err := parseINIVars(fields, groupVars[group]); err != nil {
+				return nil, fmt.Errorf("ini line %d: %v", lineNumber, err)
+			}
+			continue
+		}
+
+		host, ok := hostsByAlias[fields[0]]
+		if !ok {
+			host = &iniHost{alias: fields[0], vars: map[string]string{}}
+			hostsByAlias[host.alias] = host
+			hosts = append(hosts, host)
+		}
+		host.groups = append(host.groups, group)
+		if err := parseINIVars(fields[1:], host.vars); err != nil {
+			return nil, fmt.Errorf("ini line %d: %v", lineNumber, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	nodes := []v3.RKEConfigNode{}
+	for _, host := range hosts {
+		// group variables first so that host variables win
+		vars := map[string]string{"address": host.alias}
+		mergeVars(vars, groupVars[inventoryAllGroup])
+		roles := []string{}
+		for _, g := range host.groups {
+			mergeVars(vars, groupVars[g])
+			if isNodeRole(g) {
+				roles = append(roles, g)
+			}
+		}
+		mergeVars(vars, host.vars)
+		if _, ok := vars["role"]; !ok {
+			if len(roles) == 0 {
+				return nil, fmt.Errorf("ini host %s is in no %s group, only in %s", host.alias, strings.Join(NodeRoles, ","), strings.Join(host.groups, ","))
+			}
+			vars["role"] = strings.Join(roles, ",")
+		}
+
+		node := v3.RKEConfigNode{}
+		if err := setNodeVars(&node, vars); err != nil {
+			return nil, fmt.Errorf("ini host %s: %v", host.alias, err)
+		}
+		nodes = append(nodes, node)
+	}
+
+	return nodes, nil
+}
+
+func mergeVars(dst, src map[string]string) {
+	for key, value := range src {
+		dst[key] = value
+	}
+}
+
+func parseINIVars(fields []string, vars map[string]string) error {
+	for _, field := range fields {
+		parts := strings.SplitN(field, "=", 2)
+		if len(parts) != 2 {
+			return fmt.Errorf("variable %s is not in key=value format", field)
+		}
+		key := parts[0]
+		if mapped, ok := ansibleVars[key]; ok {
+			key = mapped
+		}
+		vars[key] = parts[1]
+	}
+	return nil
+}
+
+// splitINIFields splits the line on whitespace, keeping quoted values together.
+func splitINIFields(line string) ([]string, error) {
+	fields := []string{}
+	current := bytes.Buffer{}
+	var quote rune
+	inField := false
+
+	for _, c := range line {
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			} else {
+				current.WriteRune(c)
+			}
+		case c == '"' || c == '\'':
+			quote = c
+			inField = true
+		case c == ' ' || c == '\t':
+			if inField {
+				fields = append(fields, current.String())
+				current.Reset()
+				inField = false
+			}
+		default:
+			current.WriteRune(c)
+			inField = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote")
+	}
+	if inField {
+		fields = append(fields, current.String())
+	}
+
+	return fields, nil
+}
+
+func formatINIInventory(nodes []v3.RKEConfigNode) []byte {
+	buf := bytes.Buffer{}
+
+	buf.WriteString("[" + inventoryAllGroup + "]\n")
+	for _, node := range nodes {
+		buf.WriteString(node.Address)
+		vars := nodeVars(node)
+		for _, column := range csvColumns {
+			value := vars[column]
+			if column == "address" || column == "role" || value == "" {
+				continue
+			}
+			key := column
+			if ansibleKey, ok := iniExportVars[column]; ok {
+				key = ansibleKey
+			}
+			if strings.ContainsAny(value, " \t") {
+				value = strconv.Quote(value)
+			}
+			buf.WriteString(" " + key + "=" + value)
+		}
+		buf.WriteString("\n")
+	}
+
+	for _, role := range NodeRoles {
+		buf.WriteString("\n[" + role + "]\n")
+		for _, node := range nodes {
+			if NodeHasRole(node, role) {
+				buf.WriteString(node.Address + "\n")
+			}
+		}
+	}
+
+	return buf.Bytes()
+}
+
+// setNodeVars sets the node fields from the rke config keyed values.
+func setNodeVars(node *v3.RKEConfigNode, vars map[string]string) error {
+	for key, value := range vars {
+		if value == "" {
+			continue
+		}
+
+		switch key {
+		case "address":
+			node.Address = value
+		case "port":
+			node.Port = value
+		case "internal_address":
+			node.InternalAddress = value
+		case "role", "roles":
+			roles, err := ParseRoles(value)
+			if err != nil {
+				return err
+			}
+			node.Role = roles
+		case "hostname_override":
+			node.HostnameOverride = value
+		case "user":
+			node.User = value
+		case "docker_socket":
+			node.DockerSocket = value
+		case "ssh_agent_auth":
+			agentAuth, err := strconv.ParseBool(value)
+			if err != nil {
+				return fmt.Errorf("ssh_agent_auth %s is not a boolean", value)
+			}
+			node.SSHAgentAuth = agentAuth
+		case "ssh_key_path":
+			node.SSHKeyPath = value
+		case "labels":
+			labels, err := ParseLabels(strings.Split(value, ","))
+			if err != nil {
+				return err
+			}
+			node.Labels = labels
+		}
+	}
+
+	if node.Address == "" {
+		return fmt.Errorf("address is required")
+	}
+
+	return nil
+}
+
+// nodeVars returns the node fields keyed by the rke config keys.
+func nodeVars(node v3.RKEConfigNode) map[string]string {
+	labelKeys := []string{}
+	for key := range node.Labels {
+		labelKeys = append(labelKeys, key)
+	}
+	sort.Strings(labelKeys)
+	labels := []string{}
+	for _, key := range labelKeys {
+		labels = append(labels, key+"="+node.Labels[key])
+	}
+
+	agentAuth := ""
+	if node.SSHAgentAuth {
+		agentAuth = "true"
+	}
+
+	return map[string]string{
+		"address":           node.Address,
+		"port":              node.Port,
+		"internal_address":  node.InternalAddress,
+		"role":              strings.Join(node.Role, ","),
+		"hostname_override": node.HostnameOverride,
+		"user":              node.User,
+		"docker_socket":     node.DockerSocket,
+		"ssh_agent_auth":    agentAuth,
+		"ssh_key_path":      node.SSHKeyPath,
+		"labels":            strings.Join(labels, ","),
+	}
+}
@@ -0,0 +1,41 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+const (
+	LockFileSuffix = ".lock"
+)
+
+// FileLock is an advisory lock guarding a file across cube processes.
+type FileLock struct {
+	file *os.File
+}
+
+// LockFile blocks until the exclusive advisory lock of the file is acquired,
+// the lock is held on a sibling file so that the file itself can be replaced.
+func LockFile(filename string) (*FileLock, error) {
+	if err := os.MkdirAll(filepath.Dir(filename), 0700); err != nil {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(filename+LockFileSuffix, os.O_CREATE|os.O_RDWR, 0640)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return &FileLock{file: file}, nil
+}
+
+func (l *FileLock) Unlock() error {
+	defer l.file.Close()
+	return syscall.Flock(int(l.file.Fd()), syscall.LOCK_UN)
+}
@@ -0,0 +1,366 @@
+package util
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/rancher/rke/services"
+	"github.com/rancher/types/apis/management.cattle.io/v3"
+	"golang.org/x/crypto/ssh"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+var (
+	// NodeRoles are the roles a rke node can take.
+	NodeRoles = []string{services.ETCDRole, services.ControlRole, services.WorkerRole}
+
+	userRegexp = regexp.MustCompile("^[a-z_][a-z0-9_-]*[$]?$")
+)
+
+// ValidateAddress checks that the address is an ip or a resolvable fqdn.
+func ValidateAddress(address string) error {
+	if net.ParseIP(address) != nil {
+		return nil
+	}
+
+	if errs := validation.IsDNS1123Subdomain(address); len(errs) > 0 {
+		return fmt.Errorf("address %s is neither an ip nor a valid fqdn: %s", address, strings.Join(errs, ", "))
+	}
+
+	if _, err := net.LookupHost(address); err != nil {
+		return fmt.Errorf("address %s can not be resolved: %v", address, err)
+	}
+
+	return nil
+}
+
+// ValidateUser checks that the user is a valid unix user name.
+func ValidateUser(user string) error {
+	if !userRegexp.MatchString(user) {
+		return fmt.Errorf("user %q is not a valid unix user name", user)
+	}
+	return nil
+}
+
+// ParseRoles splits the comma separated roles and checks each of them.
+func ParseRoles(roles string) ([]string, error) {
+	result := []string{}
+	seen := map[string]bool{}
+	for _, role := range strings.Split(roles, ",") {
+		role = strings.TrimSpace(role)
+		if role == "" || seen[role] {
+			continue
+		}
+		if !isNodeRole(role) {
+			return nil, fmt.Errorf("invalid role %s, supported roles are %s", role, strings.Join(NodeRoles, ","))
+		}
+		seen[role] = true
+		result = append(result, role)
+	}
+
+	if len(result) == 0 {
+		return nil, fmt.Errorf("at least one role of %s is required", strings.Join(NodeRoles, ","))
+	}
+
+	return result, nil
+}
+
+func isNodeRole(role string) bool {
+	for _, r := range NodeRoles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateSSHKeyPath checks that the file exists and holds a private key.
+func ValidateSSHKeyPath(sshKeyPath string) error {
+	bytes, err := ioutil.ReadFile(sshKeyPath)
+	if err != nil {
+		return fmt.Errorf("can not read ssh key %s: %v", sshKeyPath, err)
+	}
+
+	if err := validatePrivateKey(bytes); err != nil {
+		return fmt.Errorf("ssh key %s is not a usable private key: %v", sshKeyPath, err)
+	}
+
+	return nil
+}
+
+// ValidateSSHKey checks that the content is a private key.
+func ValidateSSHKey(sshKey string) error {
+	if err := validatePrivateKey([]byte(sshKey)); err != nil {
+		return fmt.Errorf("ssh key is not a usable private key: %v", err)
+	}
+	return nil
+}
+
+// validatePrivateKey parses the private key, a passphrase protected key can not
+// be parsed without the passphrase and is accepted as rke asks for it.
+func validatePrivateKey(key []byte) error {
+	if isEncryptedPrivateKey(key) {
+		return nil
+	}
+	_, err := ssh.ParsePrivateKey(key)
+	return err
+}
+
+// isEncryptedPrivateKey tells whether the key is an encrypted pem or openssh
+// private key.
+func isEncryptedPrivateKey(key []byte) bool {
+	block, _ := pem.Decode(key)
+	if block == nil {
+		return false
+	}
+
+	switch block.Type {
+	case "ENCRYPTED PRIVATE KEY":
+		return true
+	case "OPENSSH PRIVATE KEY":
+		// openssh-key-v1\0 followed by the length prefixed cipher name
+		magic := []byte("openssh-key-v1\x00")
+		if !bytes.HasPrefix(block.Bytes, magic) || len(block.Bytes) < len(magic)+4 {
+			return false
+		}
+		rest := block.Bytes[len(magic):]
+		length := binary.BigEndian.Uint32(rest)
+		if uint64(len(rest)-4) < uint64(length) {
+			return false
+		}
+		return string(rest[4:4+length]) != "none"
+	default:
+		return x509.IsEncryptedPEMBlock(block)
+	}
+}
+
+// ValidatePort checks that the port is a valid tcp port number.
+func ValidatePort(port string) error {
+	number, err := strconv.Atoi(port)
+	if err != nil {
+		return fmt.Errorf("port %s is not a number", port)
+	}
+	if errs := validation.IsValidPortNum(number); len(errs) > 0 {
+		return fmt.Errorf("port %s is invalid: %s", port, strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+// ParseLabels parses the key=value pairs into node labels.
+func ParseLabels(pairs []string) (map[string]string, error) {
+	labels := map[string]string{}
+	for _, pair := range pairs {
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("label %s is not in key=value format", pair)
+		}
+		if err := ValidateLabel(parts[0], parts[1]); err != nil {
+			return nil, err
+		}
+		labels[parts[0]] = parts[1]
+	}
+	return labels, nil
+}
+
+// ValidateLabel checks the label against the kubernetes label rules.
+func ValidateLabel(key, value string) error {
+	if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+		return fmt.Errorf("label key %s is invalid: %s", key, strings.Join(errs, ", "))
+	}
+	if errs := validation.IsValidLabelValue(value); len(errs) > 0 {
+		return fmt.Errorf("label value %s is invalid: %s", value, strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+// ValidateNode checks every field of the node that the cli can set.
+func ValidateNode(node v3.RKEConfigNode) error {
+	return validateNode(nil, node)
+}
+
+// ValidateNodeChanges checks the fields of the node that differ from the
+// previous node, the unchanged fields are left as they are in the config.
+func ValidateNodeChanges(previous, node v3.RKEConfigNode) error {
+	return validateNode(&previous, node)
+}
+
+func validateNode(previous *v3.RKEConfigNode, node v3.RKEConfigNode) error {
+	if previous == nil || previous.Address != node.Address {
+		if err := ValidateAddress(node.Address); err != nil {
+			return err
+		}
+	}
+
+	if node.InternalAddress != "" && (previous == nil || previous.InternalAddress != node.InternalAddress) {
+		if err := ValidateAddress(node.InternalAddress); err != nil {
+			return fmt.Errorf("internal %v", err)
+		}
+	}
+
+	if node.Port != "" && (previous == nil || previous.Port != node.Port) {
+		if err := ValidatePort(node.Port); err != nil {
+			return err
+		}
+	}
+
+	if len(node.Role) == 0 {
+		return fmt.Errorf("at least one role of %s is required", strings.Join(NodeRoles, ","))
+	}
+	for _, role := range node.Role {
+		if !isNodeRole(role) {
+			return fmt.Errorf("invalid role %s, supported roles are %s", role, strings.Join(NodeRoles, ","))
+		}
+	}
+
+	if node.HostnameOverride != "" && (previous == nil || previous.HostnameOverride != node.HostnameOverride) {
+		if errs := validation.IsDNS1123Subdomain(node.HostnameOverride); len(errs) > 0 {
+			return fmt.Errorf("hostname override %s is invalid: %s", node.HostnameOverride, strings.Join(errs, ", "))
+		}
+	}
+
+	if previous == nil || previous.User != node.User {
+		if err := ValidateUser(node.User); err != nil {
+			return err
+		}
+	}
+
+	for key, value := range node.Labels {
+		if previous != nil {
+			if old, ok := previous.Labels[key]; ok && old == value {
+				continue
+			}
+		}
+		if err := ValidateLabel(key, value); err != nil {
+			return err
+		}
+	}
+
+	if previous != nil && previous.SSHAgentAuth == node.SSHAgentAuth &&
+		previous.SSHKey == node.SSHKey && previous.SSHKeyPath == node.SSHKeyPath {
+		return nil
+	}
+
+	// rke prefers the ssh agent, then the inline key, then the key path
+	switch {
+	case node.SSHAgentAuth:
+		return nil
+	case node.SSHKey != "":
+		return ValidateSSHKey(node.SSHKey)
+	default:
+		return ValidateSSHKeyPath(node.SSHKeyPath)
+	}
+}
+
+// FindNode returns the index of the node with the address, or -1.
+func FindNode(nodes []v3.RKEConfigNode, address string) int {
+	for index, node := range nodes {
+		if node.Address == address {
+			return index
+		}
+	}
+	return -1
+}
+
+// RemoveString returns the slice without the occurrences of s.
+func RemoveString(slice []string, s string) []string {
+	result := []string{}
+	for _, item := range slice {
+		if item != s {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+// WithoutSecrets returns the node with the secret fields cleared, so that it
+// can be printed or exported.
+func WithoutSecrets(node v3.RKEConfigNode) v3.RKEConfigNode {
+	node.SSHKey = ""
+	return node
+}
+
+// MergeNode overlays the non empty fields of the update on the node.
+func MergeNode(node, update v3.RKEConfigNode) v3.RKEConfigNode {
+	if update.Port != "" {
+		node.Port = update.Port
+	}
+	if update.InternalAddress != "" {
+		node.InternalAddress = update.InternalAddress
+	}
+	if len(update.Role) > 0 {
+		node.Role = update.Role
+	}
+	if update.HostnameOverride != "" {
+		node.HostnameOverride = update.HostnameOverride
+	}
+	if update.User != "" {
+		node.User = update.User
+	}
+	if update.DockerSocket != "" {
+		node.DockerSocket = update.DockerSocket
+	}
+	if update.SSHAgentAuth {
+		node.SSHAgentAuth = true
+	}
+	if update.SSHKey != "" {
+		node.SSHKey = update.SSHKey
+	}
+	if update.SSHKeyPath != "" {
+		node.SSHKeyPath = update.SSHKeyPath
+	}
+	if len(update.Labels) > 0 {
+		labels := map[string]string{}
+		mergeVars(labels, node.Labels)
+		mergeVars(labels, update.Labels)
+		node.Labels = labels
+	}
+	return node
+}
+
+// NodeHasRole reports whether the node takes the role.
+func NodeHasRole(node v3.RKEConfigNode, role string) bool {
+	for _, r := range node.Role {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// CountRole returns how many nodes take the role.
+func CountRole(nodes []v3.RKEConfigNode, role string) int {
+	count := 0
+	for _, node := range nodes {
+		if NodeHasRole(node, role) {
+			count++
+		}
+	}
+	return count
+}
+
+// CheckClusterNodes returns the cluster level rules the nodes violate,
+// rke needs etcd and controlplane nodes and etcd needs an odd member count for quorum.
+func CheckClusterNodes(nodes []v3.RKEConfigNode) []string {
+	warnings := []string{}
+
+	etcd := CountRole(nodes, services.ETCDRole)
+	if etcd == 0 {
+		warnings = append(warnings, "cluster has no etcd node")
+	} else if etcd%2 == 0 {
+		warnings = append(warnings, fmt.Sprintf("cluster has %d etcd nodes, an odd count is recommended for quorum", etcd))
+	}
+
+	if CountRole(nodes, services.ControlRole) == 0 {
+		warnings = append(warnings, "cluster has no controlplane node")
+	}
+
+	return warnings
+}
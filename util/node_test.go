@@ -0,0 +1,145 @@
+package util
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/pem"
+	"strings"
+	"testing"
+
+	"github.com/rancher/types/apis/management.cattle.io/v3"
+)
+
+func TestValidateSSHKey(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	der := x509.MarshalPKCS1PrivateKey(key)
+	encrypted, err := x509.EncryptPEMBlock(rand.Reader, "RSA PRIVATE KEY", der, []byte("secret"), x509.PEMCipherAES256)
+	if err != nil {
+		t.Fatalf("encrypt key: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		key     []byte
+		wantErr string
+	}{
+		{
+			name: "plain pem key",
+			key:  pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: der}),
+		},
+		{
+			name: "encrypted pem key",
+			key:  pem.EncodeToMemory(encrypted),
+		},
+		{
+			name: "encrypted pkcs8 key",
+			key:  pem.EncodeToMemory(&pem.Block{Type: "ENCRYPTED PRIVATE KEY", Bytes: []byte("data")}),
+		},
+		{
+			name: "encrypted openssh key",
+			key:  openSSHKey("aes256-ctr"),
+		},
+		{
+			name:    "plain openssh key without a key",
+			key:     openSSHKey("none"),
+			wantErr: "ssh key is not a usable private key",
+		},
+		{
+			name:    "public key",
+			key:     []byte("ssh-rsa AAAAB3NzaC1yc2E rancher@example"),
+			wantErr: "ssh key is not a usable private key",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := ValidateSSHKey(string(test.key))
+			if test.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Errorf("got error %v, want %q", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateNodeChanges(t *testing.T) {
+	previous := v3.RKEConfigNode{
+		Address:    "10.0.0.1",
+		Role:       []string{"worker"},
+		User:       "rancher",
+		SSHKeyPath: "/nonexistent/id_rsa",
+		Labels:     map[string]string{"zone": "a"},
+	}
+
+	tests := []struct {
+		name    string
+		change  func(*v3.RKEConfigNode)
+		wantErr string
+	}{
+		{
+			name:   "unchanged ssh key path",
+			change: func(node *v3.RKEConfigNode) { node.Role = []string{"etcd"} },
+		},
+		{
+			name:   "ssh agent instead of the key path",
+			change: func(node *v3.RKEConfigNode) { node.SSHAgentAuth = true },
+		},
+		{
+			name:    "changed ssh key path",
+			change:  func(node *v3.RKEConfigNode) { node.SSHKeyPath = "/nonexistent/id_ed25519" },
+			wantErr: "can not read ssh key /nonexistent/id_ed25519",
+		},
+		{
+			name:    "changed user",
+			change:  func(node *v3.RKEConfigNode) { node.User = "Rancher" },
+			wantErr: `user "Rancher" is not a valid unix user name`,
+		},
+		{
+			name:    "added label",
+			change:  func(node *v3.RKEConfigNode) { node.Labels = map[string]string{"zone": "a", "disk": "s s d"} },
+			wantErr: "label value s s d is invalid",
+		},
+		{
+			name:    "no role",
+			change:  func(node *v3.RKEConfigNode) { node.Role = nil },
+			wantErr: "at least one role",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			node := previous
+			test.change(&node)
+			err := ValidateNodeChanges(previous, node)
+			if test.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Errorf("got error %v, want %q", err, test.wantErr)
+			}
+		})
+	}
+}
+
+// openSSHKey returns an openssh private key with the cipher and no key data.
+func openSSHKey(cipher string) []byte {
+	data := []byte("openssh-key-v1\x00")
+	length := make([]byte, 4)
+	binary.BigEndian.PutUint32(length, uint32(len(cipher)))
+	data = append(data, length...)
+	data = append(data, cipher...)
+	return pem.EncodeToMemory(&pem.Block{Type: "OPENSSH PRIVATE KEY", Bytes: data})
+}
@@ -0,0 +1,33 @@
+package util
+
+import (
+	"path/filepath"
+)
+
+const (
+	DataDirDefault = "/var/lib/rancher/cube"
+	DataDirEnv     = "CUBE_DATA_DIR"
+)
+
+var (
+	dataDir = DataDirDefault
+)
+
+// SetDataDir changes the directory that all cube files are resolved against,
+// an empty value restores the default.
+func SetDataDir(dir string) {
+	if dir == "" {
+		dir = DataDirDefault
+	}
+	dataDir = filepath.Clean(dir)
+}
+
+// DataDir returns the directory that all cube files are resolved against.
+func DataDir() string {
+	return dataDir
+}
+
+// DataPath joins the elements to the data directory.
+func DataPath(elem ...string) string {
+	return filepath.Join(append([]string{dataDir}, elem...)...)
+}
@@ -0,0 +1,24 @@
+package util
+
+import (
+	"fmt"
+	"net"
+)
+
+// ValidateBindAddress checks that the address is an ip address to bind on.
+func ValidateBindAddress(address string) error {
+	if net.ParseIP(address) == nil {
+		return fmt.Errorf("bind address %s is not an ip address", address)
+	}
+	return nil
+}
+
+// CheckPortAvailable checks that the tcp port can still be bound on the address.
+func CheckPortAvailable(address, port string) error {
+	hostPort := net.JoinHostPort(address, port)
+	listener, err := net.Listen("tcp", hostPort)
+	if err != nil {
+		return fmt.Errorf("%s is not available: %v", hostPort, err)
+	}
+	return listener.Close()
+}
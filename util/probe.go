@@ -0,0 +1,59 @@
+package util
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+// probeBodyLimit bounds how much of the probe response is read for the version.
+const probeBodyLimit = 64 * 1024
+
+// ProbeResult is the outcome of a reachable http endpoint.
+type ProbeResult struct {
+	StatusCode int
+	Latency    time.Duration
+	Version    string
+}
+
+// Healthy reports whether the endpoint answered with a success status.
+func (r *ProbeResult) Healthy() bool {
+	return r.StatusCode >= http.StatusOK && r.StatusCode < http.StatusMultipleChoices
+}
+
+// ProbeHTTP requests the url and measures the latency, the version is read
+// from a json response carrying a version or gitVersion field.
+func ProbeHTTP(url string, timeout time.Duration) (*ProbeResult, error) {
+	client := &http.Client{Timeout: timeout}
+
+	start := time.Now()
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	result := &ProbeResult{
+		StatusCode: resp.StatusCode,
+		Latency:    time.Since(start),
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, probeBodyLimit))
+	if err != nil {
+		return result, nil
+	}
+	version := struct {
+		Version    string `json:"version"`
+		GitVersion string `json:"gitVersion"`
+	}{}
+	if json.Unmarshal(body, &version) == nil {
+		result.Version = version.Version
+		if result.Version == "" {
+			result.Version = version.GitVersion
+		}
+	}
+
+	return result, nil
+}
@@ -4,9 +4,15 @@ import (
 	"io/ioutil"
 
 	"github.com/rancher/types/apis/management.cattle.io/v3"
+	"github.com/sirupsen/logrus"
 	"gopkg.in/yaml.v2"
 )
 
+const (
+	RKEConfigBackupLimit  = 10
+	KubeConfigBackupLimit = 10
+)
+
 func ReadRKEConfig(filename string) (*v3.RancherKubernetesEngineConfig, error) {
 	bytes, err := ioutil.ReadFile(filename)
 	if err != nil {
@@ -22,30 +28,17 @@ func ReadRKEConfig(filename string) (*v3.RancherKubernetesEngineConfig, error) {
 	return &cluster, nil
 }
 
-func WriteRKEConfig(config *v3.RancherKubernetesEngineConfig, filename string) error {
-	bytes, err := yaml.Marshal(config)
+// WriteRKEConfigContent backs up the previous rke config and atomically replaces
+// it with the content, callers doing a read-modify-write should hold the
+// LockFile of the filename.
+func WriteRKEConfigContent(content []byte, filename string) error {
+	backup, err := BackupFile(filename, RKEConfigBackupLimit)
 	if err != nil {
 		return err
 	}
-
-	return ioutil.WriteFile(filename, bytes, 0640)
-}
-
-func MergeNodes(s ...[]v3.RKEConfigNode) (slice []v3.RKEConfigNode) {
-	switch len(s) {
-	case 0:
-		break
-	case 1:
-		slice = s[0]
-		break
-	default:
-		s1 := s[0]
-		s2 := MergeNodes(s[1:]...)
-		slice = make([]v3.RKEConfigNode, len(s1)+len(s2))
-		copy(slice, s1)
-		copy(slice[len(s1):], s2)
-		break
+	if backup != "" {
+		logrus.Debugf("backup rke config %s to %s", filename, backup)
 	}
 
-	return
+	return WriteFileAtomic(filename, content, 0640)
 }
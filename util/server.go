@@ -0,0 +1,101 @@
+package util
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+const ServerConfigName = "server_config.yml"
+
+// ServerConfig keeps the api-server container settings reused by later runs.
+// The image is kept by an upgrade so that later runs do not downgrade.
+type ServerConfig struct {
+	Image      string   `yaml:"image,omitempty" json:"image,omitempty"`
+	Env        []string `yaml:"env,omitempty" json:"env,omitempty"`
+	Volumes    []string `yaml:"volumes,omitempty" json:"volumes,omitempty"`
+	ServerArgs []string `yaml:"server_args,omitempty" json:"serverArgs,omitempty"`
+}
+
+// ReadServerConfig reads the server config, a missing file yields an empty config.
+func ReadServerConfig(filename string) (*ServerConfig, error) {
+	config := &ServerConfig{}
+
+	bytes, err := ioutil.ReadFile(filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return config, nil
+		}
+		return nil, err
+	}
+
+	if err := yaml.Unmarshal(bytes, config); err != nil {
+		return nil, fmt.Errorf("parse server config %s: %v", filename, err)
+	}
+
+	return config, nil
+}
+
+func WriteServerConfig(config *ServerConfig, filename string) error {
+	bytes, err := yaml.Marshal(config)
+	if err != nil {
+		return err
+	}
+
+	return WriteFileAtomic(filename, bytes, 0640)
+}
+
+// ParseEnv checks the KEY=VALUE environment variables, a bare KEY takes the
+// value of the local environment as docker run does.
+func ParseEnv(envs []string) ([]string, error) {
+	result := []string{}
+	for _, env := range envs {
+		parts := strings.SplitN(env, "=", 2)
+		if parts[0] == "" || strings.ContainsAny(parts[0], " \t") {
+			return nil, fmt.Errorf("env %s is not KEY=VALUE", env)
+		}
+		if len(parts) == 1 {
+			value, ok := os.LookupEnv(parts[0])
+			if !ok {
+				return nil, fmt.Errorf("env %s is not set locally", parts[0])
+			}
+			env = parts[0] + "=" + value
+		}
+		result = append(result, env)
+	}
+	return result, nil
+}
+
+// Volume is a host path bind mounted into a container.
+type Volume struct {
+	Source   string
+	Target   string
+	ReadOnly bool
+}
+
+// ParseVolume parses the host-path:container-path[:ro|rw] volume.
+func ParseVolume(volume string) (Volume, error) {
+	parts := strings.Split(volume, ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return Volume{}, fmt.Errorf("volume %s is not host-path:container-path[:ro]", volume)
+	}
+
+	v := Volume{Source: parts[0], Target: parts[1]}
+	if len(parts) == 3 {
+		switch parts[2] {
+		case "ro":
+			v.ReadOnly = true
+		case "rw":
+		default:
+			return Volume{}, fmt.Errorf("volume %s mode must be ro or rw", volume)
+		}
+	}
+	if !filepath.IsAbs(v.Source) || !filepath.IsAbs(v.Target) {
+		return Volume{}, fmt.Errorf("volume %s paths must be absolute", volume)
+	}
+	return v, nil
+}
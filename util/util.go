@@ -7,19 +7,26 @@ import (
 	"encoding/pem"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 
 	"github.com/sirupsen/logrus"
 )
 
 const (
-	RsaDirectory = "/var/lib/rancher/cube"
-	RsaBitSize   = 4096
+	RsaBitSize = 4096
 )
 
+// RsaDirectory returns the directory where the generated rsa key pair is stored.
+func RsaDirectory() string {
+	return DataDir()
+}
+
 func GenerateRSA256() error {
+	rsaDirectory := RsaDirectory()
+
 	// make sure the rsa directory is exist
-	if _, err := os.Stat(RsaDirectory); err != nil {
-		err = os.MkdirAll(RsaDirectory, os.ModeDir|0700)
+	if _, err := os.Stat(rsaDirectory); err != nil {
+		err = os.MkdirAll(rsaDirectory, os.ModeDir|0700)
 		if err != nil {
 			return err
 		}
@@ -41,13 +48,13 @@ func GenerateRSA256() error {
 
 		privateKeyBytes := PrivateKeyToPEM(privateKey)
 
-		err = WriteKeyToFile(privateKeyBytes, RsaDirectory+"/id_rsa")
+		err = WriteKeyToFile(privateKeyBytes, filepath.Join(rsaDirectory, "id_rsa"))
 		if err != nil {
 			logrus.Errorf("write private key file error: %v", err)
 			return err
 		}
 
-		err = WriteKeyToFile([]byte(publicKeyBytes), RsaDirectory+"/id_rsa.pub")
+		err = WriteKeyToFile([]byte(publicKeyBytes), filepath.Join(rsaDirectory, "id_rsa.pub"))
 		if err != nil {
 			logrus.Errorf("write public key file error: %v", err)
 			return err
@@ -104,17 +111,20 @@ func GeneratePublicKey(privateKey *rsa.PrivateKey) ([]byte, error) {
 }
 
 func CheckRSAKeyFileExist() bool {
-	if _, err := os.Stat(RsaDirectory + "/id_rsa"); err == nil {
-		if _, err = os.Stat(RsaDirectory + "/id_rsa.pub"); err == nil {
+	privateKeyFile := filepath.Join(RsaDirectory(), "id_rsa")
+	publicKeyFile := filepath.Join(RsaDirectory(), "id_rsa.pub")
+
+	if _, err := os.Stat(privateKeyFile); err == nil {
+		if _, err = os.Stat(publicKeyFile); err == nil {
 			return true
 		}
 
-		err = os.Remove(RsaDirectory + "/id_rsa.pub")
+		err = os.Remove(publicKeyFile)
 		if err != nil {
 			logrus.Errorf("remove id_rsa.pub file error: %v", err)
 		}
 
-		err = os.Remove(RsaDirectory + "/id_rsa")
+		err = os.Remove(privateKeyFile)
 		if err != nil {
 			logrus.Errorf("remove id_rsa file error: %v", err)
 		}